@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/1lann/promptui"
+	"github.com/cli/go-gh/pkg/repository"
+	"gopkg.in/yaml.v3"
+
+	"ariga.io/gh-atlas/gen"
+)
+
+// RequireCheckCmd is the command for requiring the Atlas check for merging into the default branch.
+type RequireCheckCmd struct {
+	Repo   string    `short:"R" help:"GitHub repository <owner>/<name>, defaults to the current repository."`
+	Check  string    `help:"Name of the check to require, defaults to the check of the Atlas workflow."`
+	stdout io.Writer `hidden:""`
+	outputFlags
+}
+
+// checkResult describes where a check is required.
+type checkResult struct {
+	Check  string `json:"check"`
+	Branch string `json:"branch"`
+	// RequiredBy is either "branch protection" or "ruleset".
+	RequiredBy string `json:"requiredBy"`
+	// Added is false if the check was already required.
+	Added bool `json:"added"`
+}
+
+func (c *RequireCheckCmd) Help() string {
+	return `Run this command after merging the pull request created by init-action. The check is
+added to the required status checks of the default branch protection, or to a new ruleset
+if the branch protection does not require status checks. Requires admin access to the repository.
+
+Examples:
+	gh atlas require-check
+	gh atlas require-check -R ariga/atlas-demo --check=lint
+	gh atlas require-check --json`
+}
+
+// Run the require-check command.
+func (c *RequireCheckCmd) Run(ctx context.Context, client *githubClient, current repository.Repository) error {
+	var err error
+	if c.Repo != "" {
+		if current, err = repository.Parse(c.Repo); err != nil {
+			return err
+		}
+	}
+	repoData, _, err := client.Repositories.Get(ctx, current.Owner(), current.Name())
+	if err != nil {
+		return err
+	}
+	repo := NewRepository(client, current, repoData.GetDefaultBranch())
+	switch content, err := repo.ReadContent(ctx, workflowPath); {
+	case err != nil && c.Check == "":
+		return fmt.Errorf("failed to read %s from the default branch, make sure the pull request created by init-action is merged: %w", workflowPath, err)
+	case err == nil:
+		if paths := prPaths(content); len(paths) > 0 {
+			return fmt.Errorf("%s runs only on pull requests changing %s, and other pull requests would wait for the required check forever. Regenerate it with `gh atlas init-action --require-check`, which checks the paths in the job instead", workflowPath, strings.Join(paths, ", "))
+		}
+		if c.Check == "" {
+			c.Check = workflowCheck(content)
+		}
+	}
+	w := c.stdout
+	if w == nil {
+		w = os.Stdout
+	}
+	if !c.enabled() {
+		_, err = requireCheck(ctx, w, repo, c.Check)
+		return err
+	}
+	res, err := requireCheck(ctx, io.Discard, repo, c.Check)
+	if err != nil {
+		return err
+	}
+	return c.print(w, res)
+}
+
+// workflowCheck returns the name of the check reported by the generated workflow.
+// The flow is told by the IDs of the jobs, as the actions may be used from mirrors.
+func workflowCheck(content string) string {
+	var w struct {
+		Jobs map[string]any `yaml:"jobs"`
+	}
+	cfg := &gen.Config{Flow: string(Declarative)}
+	if err := yaml.Unmarshal([]byte(content), &w); err != nil || w.Jobs[cfg.CheckName()] == nil {
+		cfg.Flow = string(Versioned)
+	}
+	return cfg.CheckName()
+}
+
+// prPaths returns the path filters of the pull_request event of the workflow, if any.
+func prPaths(content string) []string {
+	var w struct {
+		On struct {
+			PullRequest struct {
+				Paths []string `yaml:"paths"`
+			} `yaml:"pull_request"`
+		} `yaml:"on"`
+	}
+	// Other forms of the on key, e.g. a list of events, cannot filter paths.
+	if err := yaml.Unmarshal([]byte(content), &w); err != nil {
+		return nil
+	}
+	return w.On.PullRequest.Paths
+}
+
+// requireCheck requires the check for merging into the default branch of the repository.
+func requireCheck(ctx context.Context, w io.Writer, repo *Repository, check string) (*checkResult, error) {
+	by, added, err := repo.RequireCheck(ctx, check)
+	if err != nil {
+		return nil, err
+	}
+	res := &checkResult{Check: check, Branch: repo.defaultBranch, RequiredBy: by, Added: added}
+	if !added {
+		fmt.Fprintf(w, "%s %s %q is already required by the %s of %s\n",
+			promptui.IconGood,
+			promptui.Styler(promptui.FGFaint)("Check"),
+			check, by, repo.defaultBranch,
+		)
+		return res, nil
+	}
+	fmt.Fprintf(w, "%s %s %q to the %s of %s\n",
+		promptui.IconGood,
+		promptui.Styler(promptui.FGFaint)("Added required check"),
+		check, by, repo.defaultBranch,
+	)
+	return res, nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/cli/go-gh/pkg/repository"
+	"github.com/stretchr/testify/require"
+
+	"ariga.io/gh-atlas/gen"
+)
+
+func TestRequireCheckCmd(t *testing.T) {
+	current, err := repository.Parse("ariga/gh-atlas")
+	require.NoError(t, err)
+
+	gh := &fakeGitHub{rules: `[]`}
+	cmd := &RequireCheckCmd{stdout: &bytes.Buffer{}}
+	err = cmd.Run(context.Background(), gh.client(t), current)
+	require.ErrorContains(t, err, "failed to read .github/workflows/ci-atlas.yaml from the default branch, make sure the pull request created by init-action is merged")
+
+	gh = &fakeGitHub{rules: `[]`, workflow: "jobs:\n  plan:\n    steps:\n      - uses: ariga/atlas-action/schema/plan@v1\n"}
+	out := &bytes.Buffer{}
+	cmd = &RequireCheckCmd{stdout: out}
+	require.NoError(t, cmd.Run(context.Background(), gh.client(t), current))
+	require.Equal(t, "plan", cmd.Check)
+	require.Contains(t, out.String(), `"plan" to the ruleset of master`)
+
+	// Pull requests not changing the filtered paths would never report the check.
+	gh = &fakeGitHub{rules: `[]`, workflow: "on:\n  pull_request:\n    paths:\n      - 'migrations/**'\njobs:\n  atlas:\n    steps:\n      - uses: ariga/atlas-action/migrate/lint@v1\n"}
+	cmd = &RequireCheckCmd{Check: "atlas", stdout: &bytes.Buffer{}}
+	err = cmd.Run(context.Background(), gh.client(t), current)
+	require.ErrorContains(t, err, ".github/workflows/ci-atlas.yaml runs only on pull requests changing migrations/**")
+
+	gh = &fakeGitHub{rules: `[]`, protection: `{"required_status_checks":{"contexts":["lint"]}}`}
+	out = &bytes.Buffer{}
+	cmd = &RequireCheckCmd{Repo: "ariga/gh-atlas", Check: "lint", stdout: out}
+	require.NoError(t, cmd.Run(context.Background(), gh.client(t), nil))
+	require.Contains(t, out.String(), `"lint" is already required by the branch protection of master`)
+
+	out = &bytes.Buffer{}
+	cmd = &RequireCheckCmd{Repo: "ariga/gh-atlas", Check: "lint", outputFlags: outputFlags{JSON: true}, stdout: out}
+	require.NoError(t, cmd.Run(context.Background(), gh.client(t), nil))
+	require.JSONEq(t, `{"check":"lint","branch":"master","requiredBy":"branch protection","added":false}`, out.String())
+}
+
+func TestWorkflowCheck(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		cfg  *gen.Config
+	}{
+		{name: "versioned", cfg: &gen.Config{Flow: "versioned", Path: "migrations", DirName: "app"}},
+		{name: "declarative", cfg: &gen.Config{Flow: "declarative", From: "atlas://app", To: "file://schema.hcl", CloudRepo: "app"}},
+		{
+			name: "mirrored declarative",
+			cfg: &gen.Config{
+				Flow:      "declarative",
+				From:      "atlas://app",
+				To:        "file://schema.hcl",
+				CloudRepo: "app",
+				Mirrors:   map[string]string{"ariga/atlas-action": "actions-mirror/atlas-action"},
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.DefaultBranch, tt.cfg.SecretName, tt.cfg.Driver = "master", "ATLAS_CLOUD_TOKEN", "MYSQL"
+			content, err := gen.Generate(tt.cfg)
+			require.NoError(t, err)
+			require.Equal(t, tt.cfg.CheckName(), workflowCheck(string(content)))
+		})
+	}
+	require.Equal(t, "atlas", workflowCheck("on: [push"))
+}
+
+func TestPRPaths(t *testing.T) {
+	require.Equal(t, []string{"migrations/**"}, prPaths("on:\n  push:\n    paths: [ a ]\n  pull_request:\n    paths:\n      - 'migrations/**'\n"))
+	require.Empty(t, prPaths("on:\n  push:\n    paths: [ a ]\n  pull_request:\n"))
+	require.Empty(t, prPaths("on: [push, pull_request]\n"))
+}
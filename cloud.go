@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pkg/browser"
+
+	"ariga.io/gh-atlas/cloudapi"
+)
+
+type (
+	// cloudFlags are the flags shared by the commands reading from Atlas Cloud.
+	cloudFlags struct {
+		Token        string        `short:"t" env:"ATLAS_CLOUD_TOKEN" help:"Atlas authentication token."`
+		CloudURL     string        `name:"cloud-url" env:"ATLAS_CLOUD_URL" placeholder:"URL" help:"URL of the Atlas Cloud API, defaults to ${cloud_url}."`
+		api          cloudapi.API  `hidden:""`
+		stdout       io.Writer     `hidden:""`
+		tokenSources []tokenSource `hidden:""`
+	}
+	// CloudCmd groups the commands for browsing Atlas Cloud.
+	CloudCmd struct {
+		cloudFlags
+		Repos  CloudReposCmd  `cmd:"" help:"List Atlas Cloud repositories."`
+		Open   CloudOpenCmd   `cmd:"" help:"Open an Atlas Cloud repository in the browser."`
+		Whoami CloudWhoamiCmd `cmd:"" help:"Show the identity and permissions of the Atlas token."`
+	}
+	// CloudReposCmd is the command for listing Atlas Cloud repositories.
+	CloudReposCmd struct {
+		Type   cloudapi.RepoType `optional:"" help:"Filter by repository type (SCHEMA, MIGRATION_DIRECTORY)."`
+		Driver string            `optional:"" help:"Filter by driver."`
+		Search string            `optional:"" help:"Filter by repository name."`
+		outputFlags
+	}
+	// CloudOpenCmd is the command for opening an Atlas Cloud repository in the browser.
+	CloudOpenCmd struct {
+		Slug string `arg:"" help:"Slug of the repository to open."`
+		outputFlags
+	}
+	// CloudWhoamiCmd is the command for showing the identity of the Atlas token.
+	CloudWhoamiCmd struct {
+		outputFlags
+	}
+)
+
+func (c *CloudCmd) Help() string {
+	return `The Atlas Cloud token is resolved the same way as in the init-action command,
+except that it is never prompted for.
+
+Examples:
+	gh atlas cloud repos --type=MIGRATION_DIRECTORY
+	gh atlas cloud repos --search=app --json
+	gh atlas cloud repos --jq '.[].slug'
+	gh atlas cloud open app
+	gh atlas cloud whoami`
+}
+
+// client returns the Atlas Cloud client, resolving the token
+// from its sources if it was not given by flag or environment.
+func (c *cloudFlags) client() (cloudapi.API, error) {
+	if c.api != nil {
+		return c.api, nil
+	}
+	if c.Token == "" {
+		token, _, err := lookupToken(c.tokenSources, c.CloudURL)
+		if err != nil {
+			return nil, err
+		}
+		if token == "" {
+			return nil, errors.New("no Atlas Cloud token found, use --token or set ATLAS_CLOUD_TOKEN")
+		}
+		c.Token = token
+	}
+	c.api = cloudapi.New(c.CloudURL, c.Token)
+	return c.api, nil
+}
+
+// out returns the writer for the command output.
+func (c *cloudFlags) out() io.Writer {
+	if c.stdout != nil {
+		return c.stdout
+	}
+	return os.Stdout
+}
+
+// Run the cloud repos command.
+func (r *CloudReposCmd) Run(ctx context.Context, cloud *CloudCmd) error {
+	api, err := cloud.client()
+	if err != nil {
+		return err
+	}
+	var (
+		repos []cloudapi.Repo
+		input = cloudapi.ReposInput{First: reposPageSize}
+	)
+	if r.Type != "" || r.Driver != "" || r.Search != "" {
+		input.Filter = &cloudapi.ReposFilter{Type: r.Type, Driver: r.Driver, Search: r.Search}
+	}
+	for {
+		page, err := api.Repos(ctx, input)
+		if err != nil {
+			return err
+		}
+		repos = append(repos, page.Repos...)
+		if !page.PageInfo.HasNextPage {
+			break
+		}
+		input.After = page.PageInfo.EndCursor
+	}
+	if r.enabled() {
+		return r.print(cloud.out(), repos)
+	}
+	w := tabwriter.NewWriter(cloud.out(), 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SLUG\tTYPE\tDRIVER\tURL")
+	for _, repo := range repos {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", repo.Slug, repo.Type, repo.Driver, repo.URL)
+	}
+	return w.Flush()
+}
+
+// Run the cloud open command.
+func (o *CloudOpenCmd) Run(ctx context.Context, cloud *CloudCmd) error {
+	api, err := cloud.client()
+	if err != nil {
+		return err
+	}
+	repo, err := api.Repo(ctx, o.Slug)
+	if err != nil {
+		return err
+	}
+	// In JSON mode, the repository is printed instead of opened.
+	if o.enabled() {
+		return o.print(cloud.out(), repo)
+	}
+	if repo.Link == "" {
+		return fmt.Errorf("repository %q has no link", o.Slug)
+	}
+	fmt.Fprintf(cloud.out(), "Opening %s in your browser.\n", repo.Link)
+	return browser.OpenURL(repo.Link)
+}
+
+// Run the cloud whoami command.
+func (w *CloudWhoamiCmd) Run(ctx context.Context, cloud *CloudCmd) error {
+	api, err := cloud.client()
+	if err != nil {
+		return err
+	}
+	info, err := api.TokenInfo(ctx)
+	if err != nil {
+		return err
+	}
+	if w.enabled() {
+		return w.print(cloud.out(), info)
+	}
+	fmt.Fprintf(cloud.out(), "Name:         %s\n", info.Name)
+	fmt.Fprintf(cloud.out(), "Organization: %s\n", info.Org)
+	fmt.Fprintf(cloud.out(), "Token type:   %s\n", info.Type)
+	fmt.Fprintf(cloud.out(), "Scopes:       %s\n", joinOr(info.Scopes, "none"))
+	fmt.Fprintf(cloud.out(), "Repositories: %s\n", joinOr(info.Repos, "all"))
+	return nil
+}
+
+// printJSON writes v to w as indented JSON.
+func printJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// joinOr joins the values with commas, or returns def if there are none.
+func joinOr(values []string, def string) string {
+	if len(values) == 0 {
+		return def
+	}
+	return strings.Join(values, ", ")
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"ariga.io/gh-atlas/cloudapi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCloudReposCmd(t *testing.T) {
+	api := &mockCloudAPI{
+		pageSize: 1,
+		repos: []cloudapi.Repo{
+			{Slug: "app", Type: cloudapi.DirectoryType, Driver: "MYSQL", URL: "atlas://app"},
+			{Slug: "schema", Type: cloudapi.SchemaType, Driver: "POSTGRESQL", URL: "atlas://schema"},
+		},
+	}
+	var out bytes.Buffer
+	cloud := &CloudCmd{cloudFlags: cloudFlags{api: api, stdout: &out}}
+	require.NoError(t, (&CloudReposCmd{}).Run(context.Background(), cloud))
+	require.Equal(t, `SLUG    TYPE                 DRIVER      URL
+app     MIGRATION_DIRECTORY  MYSQL       atlas://app
+schema  SCHEMA               POSTGRESQL  atlas://schema
+`, out.String())
+
+	out.Reset()
+	require.NoError(t, (&CloudReposCmd{outputFlags: outputFlags{JSON: true}}).Run(context.Background(), cloud))
+	var repos []cloudapi.Repo
+	require.NoError(t, json.Unmarshal(out.Bytes(), &repos))
+	require.Equal(t, api.repos, repos)
+
+	out.Reset()
+	require.NoError(t, (&CloudReposCmd{outputFlags: outputFlags{JQ: ".[].slug"}}).Run(context.Background(), cloud))
+	require.Equal(t, "app\nschema\n", out.String())
+
+	out.Reset()
+	require.NoError(t, (&CloudReposCmd{outputFlags: outputFlags{Template: `{{range .}}{{.slug}}={{.driver}} {{end}}`}}).Run(context.Background(), cloud))
+	require.Equal(t, "app=MYSQL schema=POSTGRESQL ", out.String())
+}
+
+func TestCloudOpenCmd(t *testing.T) {
+	var out bytes.Buffer
+	cloud := &CloudCmd{cloudFlags: cloudFlags{
+		api:    &mockCloudAPI{repos: []cloudapi.Repo{{Slug: "app"}}},
+		stdout: &out,
+	}}
+	err := (&CloudOpenCmd{Slug: "app"}).Run(context.Background(), cloud)
+	require.EqualError(t, err, `repository "app" has no link`)
+	err = (&CloudOpenCmd{Slug: "unknown"}).Run(context.Background(), cloud)
+	require.ErrorIs(t, err, cloudapi.ErrNotFound)
+	require.NoError(t, (&CloudOpenCmd{Slug: "app", outputFlags: outputFlags{JQ: ".slug"}}).Run(context.Background(), cloud))
+	require.Equal(t, "app\n", out.String())
+}
+
+func TestCloudWhoamiCmd(t *testing.T) {
+	var out bytes.Buffer
+	cloud := &CloudCmd{cloudFlags: cloudFlags{
+		api: &mockCloudAPI{tokenInfo: &cloudapi.TokenInfo{
+			Type:   cloudapi.BotToken,
+			Name:   "ci",
+			Org:    "ariga",
+			Scopes: []string{cloudapi.ScopeMigratePush, cloudapi.ScopeSchemaPush},
+		}},
+		stdout: &out,
+	}}
+	require.NoError(t, (&CloudWhoamiCmd{}).Run(context.Background(), cloud))
+	require.Equal(t, `Name:         ci
+Organization: ariga
+Token type:   BOT
+Scopes:       MIGRATE_PUSH, SCHEMA_PUSH
+Repositories: all
+`, out.String())
+}
+
+func TestCloudFlags_client(t *testing.T) {
+	cloud := &cloudFlags{}
+	_, err := cloud.client()
+	require.EqualError(t, err, "no Atlas Cloud token found, use --token or set ATLAS_CLOUD_TOKEN")
+
+	cloud = &cloudFlags{
+		tokenSources: []tokenSource{
+			{name: "file", token: func(string) (string, error) { return "file-token", nil }},
+		},
+	}
+	api, err := cloud.client()
+	require.NoError(t, err)
+	require.NotNil(t, api)
+	require.Equal(t, "file-token", cloud.Token)
+}
@@ -4,17 +4,31 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"math/rand"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/vektah/gqlparser/v2/gqlerror"
-
-	"time"
 )
 
-// defaultURL for Atlas Cloud.
-const defaultURL = "https://api.atlasgo.cloud/query"
+// DefaultURL of the Atlas Cloud API.
+const DefaultURL = "https://api.atlasgo.cloud/query"
+
+// Default retry policy of the client.
+const (
+	defaultRetries    = 3
+	defaultBaseDelay  = 500 * time.Millisecond
+	defaultMaxBackoff = 10 * time.Second
+	// maxRetryAfter is the longest delay requested by the server that is waited
+	// before retrying. Requests asked to wait longer fail with the requested delay.
+	maxRetryAfter = time.Minute
+)
 
 // roundTripper is a http.RoundTripper that adds the Authorization header.
 type roundTripper struct {
@@ -33,23 +47,132 @@ type (
 	// API defines how to interact with the Atlas Cloud API.
 	API interface {
 		ValidateToken(ctx context.Context) error
-		Repos(ctx context.Context) ([]Repo, error)
+		Repos(ctx context.Context, input ReposInput) (*ReposPage, error)
+		CreateRepo(ctx context.Context, input CreateRepoInput) (*Repo, error)
+		TokenInfo(ctx context.Context) (*TokenInfo, error)
+		Repo(ctx context.Context, slug string) (*Repo, error)
+		Reports(ctx context.Context, input ReportsInput) ([]Report, error)
+		Plans(ctx context.Context, input PlansInput) ([]Plan, error)
+		ApprovePlan(ctx context.Context, name string) (*Plan, error)
+		RejectPlan(ctx context.Context, name string) (*Plan, error)
 	}
 	// Client is a client for the Atlas Cloud API.
 	Client struct {
-		client   *http.Client
-		endpoint string
-		token    string
+		client     *http.Client
+		endpoint   string
+		token      string
+		retries    int
+		baseDelay  time.Duration
+		maxBackoff time.Duration
 	}
+	// Option configures a Client.
+	Option func(*Client)
 	// RepoType represents the type of an Atlas Cloud repository.
 	RepoType string
 	// Repo represents a project in the Atlas Cloud API.
 	Repo struct {
-		URL    string
-		Title  string
-		Slug   string
-		Type   RepoType
-		Driver string
+		URL    string   `json:"url"`
+		Title  string   `json:"title"`
+		Slug   string   `json:"slug"`
+		Type   RepoType `json:"type"`
+		Driver string   `json:"driver"`
+		// Link is the URL of the repository in the Atlas Cloud UI.
+		Link string `json:"link,omitempty"`
+	}
+	// ReposInput holds the pagination and filtering options for listing repositories.
+	ReposInput struct {
+		// First is the maximum number of repositories to return.
+		First int `json:"first,omitempty"`
+		// After is the cursor to start listing from, as returned in PageInfo.EndCursor.
+		After string `json:"after,omitempty"`
+		// Filter is applied by the server before paginating.
+		Filter *ReposFilter `json:"filter,omitempty"`
+	}
+	// ReposFilter filters the repositories returned by the Atlas Cloud API.
+	ReposFilter struct {
+		Type   RepoType `json:"type,omitempty"`
+		Driver string   `json:"driver,omitempty"`
+		// Search matches repositories by their title or slug.
+		Search string `json:"search,omitempty"`
+	}
+	// ReposPage is a single page of repositories.
+	ReposPage struct {
+		Repos    []Repo   `json:"repos"`
+		PageInfo PageInfo `json:"pageInfo"`
+	}
+	// CreateRepoInput holds the attributes of a new Atlas Cloud repository.
+	CreateRepoInput struct {
+		Slug   string   `json:"slug"`
+		Type   RepoType `json:"type"`
+		Driver string   `json:"driver"`
+	}
+	// TokenType represents the kind of an Atlas Cloud token.
+	TokenType string
+	// TokenInfo describes the identity and the permissions of an Atlas Cloud token.
+	TokenInfo struct {
+		Type TokenType `json:"type"`
+		// Name is the name of the bot, or the email of the user owning the token.
+		Name string `json:"name"`
+		Org  string `json:"org"`
+		// Scopes are the permissions granted to the token.
+		Scopes []string `json:"scopes"`
+		// Repos are the slugs of the repositories the token is limited
+		// to. An empty list means the token can access all repositories.
+		Repos []string `json:"repos"`
+	}
+	// ReportsInput identifies the CI runs to fetch reports for.
+	ReportsInput struct {
+		// Repo is the GitHub repository in the <owner>/<name> format.
+		Repo string `json:"repo"`
+		// Commit is the SHA of the commit the CI ran on.
+		Commit string `json:"commit"`
+	}
+	// ReportType represents the kind of a CI report.
+	ReportType string
+	// Report is the result of a CI run reported to the Atlas Cloud.
+	Report struct {
+		Type   ReportType `json:"type"`
+		Status string     `json:"status"`
+		// URL is the link to the report in the Atlas Cloud UI.
+		URL         string       `json:"url"`
+		Diagnostics []Diagnostic `json:"diagnostics"`
+	}
+	// Severity represents the severity of a diagnostic.
+	Severity string
+	// Diagnostic is a single issue found by a CI run.
+	Diagnostic struct {
+		File     string   `json:"file"`
+		Code     string   `json:"code"`
+		Message  string   `json:"message"`
+		Severity Severity `json:"severity"`
+	}
+	// PlansInput filters the declarative schema plans. Plans are looked up
+	// by name, or by the pull request of the GitHub repository they were created for.
+	PlansInput struct {
+		Name string `json:"name,omitempty"`
+		// Repo is the GitHub repository in the <owner>/<name> format.
+		Repo        string     `json:"repo,omitempty"`
+		PullRequest int        `json:"pullRequest,omitempty"`
+		Status      PlanStatus `json:"status,omitempty"`
+	}
+	// PlanStatus represents the review status of a schema plan.
+	PlanStatus string
+	// Plan is a declarative schema plan created by the CI.
+	Plan struct {
+		Name   string     `json:"name"`
+		Status PlanStatus `json:"status"`
+		// Schema is the slug of the schema repository the plan belongs to.
+		Schema      string `json:"schema"`
+		PullRequest int    `json:"pullRequest"`
+		// URL is the link to the plan in the Atlas Cloud UI.
+		URL string `json:"url"`
+		// Migration is the SQL migration planned.
+		Migration string `json:"migration"`
+	}
+	// PageInfo holds the cursor information of a paginated result.
+	PageInfo struct {
+		HasNextPage bool   `json:"hasNextPage"`
+		EndCursor   string `json:"endCursor"`
 	}
 )
 
@@ -60,12 +183,65 @@ const (
 	DirectoryType RepoType = "MIGRATION_DIRECTORY"
 )
 
+// WithRetries sets the maximum number of times a failed request is retried.
+// Zero disables retries.
+func WithRetries(n int) Option {
+	return func(c *Client) {
+		c.retries = n
+	}
+}
+
+// WithBackoff sets the base and the maximum delay of the exponential backoff between retries.
+func WithBackoff(base, maxDelay time.Duration) Option {
+	return func(c *Client) {
+		c.baseDelay, c.maxBackoff = base, maxDelay
+	}
+}
+
+const (
+	BotToken      TokenType = "BOT"
+	PersonalToken TokenType = "PERSONAL"
+)
+
+const (
+	LintReport ReportType = "LINT"
+	PlanReport ReportType = "PLAN"
+)
+
+const (
+	SeverityError   Severity = "ERROR"
+	SeverityWarning Severity = "WARNING"
+	SeverityInfo    Severity = "INFO"
+)
+
+const (
+	PlanPending  PlanStatus = "PENDING"
+	PlanApproved PlanStatus = "APPROVED"
+	PlanRejected PlanStatus = "REJECTED"
+)
+
+// Token scopes required by the CI workflows.
+const (
+	ScopeMigratePush = "MIGRATE_PUSH"
+	ScopeSchemaPush  = "SCHEMA_PUSH"
+)
+
+// CanPush reports whether the token is allowed to push to the given repository,
+// using `migrate push` for migration directories and `schema push` for schemas.
+func (t *TokenInfo) CanPush(r Repo) bool {
+	scope := ScopeMigratePush
+	if r.Type == SchemaType {
+		scope = ScopeSchemaPush
+	}
+	return slices.Contains(t.Scopes, scope) && (len(t.Repos) == 0 || slices.Contains(t.Repos, r.Slug))
+}
+
 // New creates a new Client for the Atlas Cloud API.
-func New(endpoint, token string) *Client {
+func New(endpoint, token string, opts ...Option) *Client {
 	if endpoint == "" {
-		endpoint = defaultURL
+		endpoint = DefaultURL
 	}
-	return &Client{
+	c := &Client{
 		endpoint: endpoint,
 		client: &http.Client{
 			Transport: &roundTripper{
@@ -73,12 +249,100 @@ func New(endpoint, token string) *Client {
 			},
 			Timeout: time.Second * 30,
 		},
-		token: token,
+		token:      token,
+		retries:    defaultRetries,
+		baseDelay:  defaultBaseDelay,
+		maxBackoff: defaultMaxBackoff,
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Endpoint returns the URL of the Atlas Cloud API used by the client.
+func (c *Client) Endpoint() string {
+	return c.endpoint
 }
 
-// sends a POST request to the Atlas Cloud API.
+// post sends a POST request to the Atlas Cloud API. Rate-limited requests are
+// always retried, while transport errors and server errors are retried only for
+// queries, as mutations are not guaranteed to be idempotent.
 func (c *Client) post(ctx context.Context, query string, vars, data any) error {
+	return c.send(ctx, query, vars, data, !strings.HasPrefix(strings.TrimSpace(query), "mutation"))
+}
+
+// postReadOnly sends a mutation that does not change any state, e.g. validateToken,
+// and is retried like queries.
+func (c *Client) postReadOnly(ctx context.Context, query string, vars, data any) error {
+	return c.send(ctx, query, vars, data, true)
+}
+
+// send sends the request, and retries it on failure. The delay requested by the
+// server with the Retry-After header is honored, unless it exceeds maxRetryAfter.
+func (c *Client) send(ctx context.Context, query string, vars, data any, idempotent bool) error {
+	for attempt := 0; ; attempt++ {
+		err := c.do(ctx, query, vars, data)
+		if err == nil || attempt >= c.retries || ctx.Err() != nil {
+			return err
+		}
+		var (
+			rl    *RateLimitError
+			te    *TransportError
+			se    *StatusError
+			delay = c.backoff(attempt)
+		)
+		switch {
+		case errors.As(err, &rl):
+			delay = max(delay, rl.RetryAfter)
+		case idempotent && errors.As(err, &te):
+		case idempotent && errors.As(err, &se) && se.StatusCode >= http.StatusInternalServerError:
+			delay = max(delay, se.RetryAfter)
+		default:
+			return err
+		}
+		if delay > maxRetryAfter {
+			return err
+		}
+		t := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return err
+		case <-t.C:
+		}
+	}
+}
+
+// backoff returns the delay before the given retry attempt, using exponential backoff with full jitter.
+func (c *Client) backoff(attempt int) time.Duration {
+	d := c.maxBackoff
+	if attempt < 32 {
+		d = min(c.baseDelay<<attempt, c.maxBackoff)
+	}
+	if d <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(d)) + 1)
+}
+
+// retryAfter parses the Retry-After header, given in seconds or as an HTTP date.
+func retryAfter(res *http.Response) time.Duration {
+	v := res.Header.Get("Retry-After")
+	if v == "" {
+		return 0
+	}
+	if s, err := strconv.Atoi(v); err == nil && s > 0 {
+		return time.Duration(s) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(time.Until(t), 0)
+	}
+	return 0
+}
+
+// do sends a single request to the Atlas Cloud API.
+func (c *Client) do(ctx context.Context, query string, vars, data any) error {
 	body, err := json.Marshal(struct {
 		Query     string `json:"query"`
 		Variables any    `json:"variables,omitempty"`
@@ -95,12 +359,12 @@ func (c *Client) post(ctx context.Context, query string, vars, data any) error {
 	}
 	res, err := c.client.Do(req)
 	if err != nil {
-		return err
+		return &TransportError{Err: err}
 	}
 	defer res.Body.Close()
 	if res.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(res.Body)
-		return fmt.Errorf("unexpected status code: %d: %s", res.StatusCode, bytes.TrimSpace(body))
+		return statusError(res, string(bytes.TrimSpace(body)))
 	}
 	var scan = struct {
 		Data   any           `json:"data"`
@@ -112,7 +376,7 @@ func (c *Client) post(ctx context.Context, query string, vars, data any) error {
 		return err
 	}
 	if len(scan.Errors) > 0 {
-		return scan.Errors
+		return &GraphQLError{Errors: scan.Errors}
 	}
 	return nil
 }
@@ -136,21 +400,167 @@ func (c *Client) ValidateToken(ctx context.Context) error {
 			Token: c.token,
 		}
 	)
-	return c.post(ctx, query, vars, &payload)
+	return c.postReadOnly(ctx, query, vars, &payload)
 }
 
-// Repos fetches data of all repositories from the Atlas Cloud.
-func (c *Client) Repos(ctx context.Context) ([]Repo, error) {
+// Repos fetches a single page of repositories from the Atlas Cloud.
+func (c *Client) Repos(ctx context.Context, input ReposInput) (*ReposPage, error) {
 	var (
 		payload struct {
-			Repos struct {
-				Repos []Repo `json:"repos"`
-			} `json:"repos"`
+			Repos ReposPage `json:"repos"`
+		}
+		query = `query repos($first: Int, $after: String, $filter: ReposFilter) {
+		repos(first: $first, after: $after, filter: $filter) {
+			repos { title slug url type driver link }
+			pageInfo { hasNextPage endCursor }
 		}
-		query = `query { repos { repos { title slug url type driver } } }`
+	}`
+	)
+	if err := c.post(ctx, query, input, &payload); err != nil {
+		return nil, err
+	}
+	return &payload.Repos, nil
+}
+
+// Repo fetches the repository with the given slug from the Atlas Cloud.
+func (c *Client) Repo(ctx context.Context, slug string) (*Repo, error) {
+	var (
+		payload struct {
+			Repo *Repo `json:"repo"`
+		}
+		query = `query repo($slug: String!) {
+		repo(slug: $slug) {
+			title slug url type driver link
+		}
+	}`
+		vars = struct {
+			Slug string `json:"slug"`
+		}{
+			Slug: slug,
+		}
+	)
+	if err := c.post(ctx, query, vars, &payload); err != nil {
+		return nil, err
+	}
+	if payload.Repo == nil {
+		return nil, fmt.Errorf("repository %q: %w", slug, ErrNotFound)
+	}
+	return payload.Repo, nil
+}
+
+// CreateRepo creates a new repository in the Atlas Cloud.
+func (c *Client) CreateRepo(ctx context.Context, input CreateRepoInput) (*Repo, error) {
+	var (
+		payload struct {
+			CreateRepo Repo `json:"createRepo"`
+		}
+		query = `mutation createRepo($input: CreateRepoInput!) {
+		createRepo(input: $input) {
+			title slug url type driver link
+		}
+	}`
+		vars = struct {
+			Input CreateRepoInput `json:"input"`
+		}{
+			Input: input,
+		}
+	)
+	if err := c.post(ctx, query, vars, &payload); err != nil {
+		return nil, err
+	}
+	return &payload.CreateRepo, nil
+}
+
+// TokenInfo returns the identity and the permissions of the token inside the client.
+func (c *Client) TokenInfo(ctx context.Context) (*TokenInfo, error) {
+	var (
+		payload struct {
+			TokenInfo TokenInfo `json:"tokenInfo"`
+		}
+		query = `query { tokenInfo { type name org scopes repos } }`
 	)
 	if err := c.post(ctx, query, nil, &payload); err != nil {
 		return nil, err
 	}
-	return payload.Repos.Repos, nil
+	return &payload.TokenInfo, nil
+}
+
+// Reports fetches the lint and schema plan reports of the CI runs on the given commit.
+func (c *Client) Reports(ctx context.Context, input ReportsInput) ([]Report, error) {
+	var (
+		payload struct {
+			Reports []Report `json:"ciReports"`
+		}
+		query = `query ciReports($input: CIReportsInput!) {
+		ciReports(input: $input) {
+			type status url
+			diagnostics { file code message severity }
+		}
+	}`
+		vars = struct {
+			Input ReportsInput `json:"input"`
+		}{
+			Input: input,
+		}
+	)
+	if err := c.post(ctx, query, vars, &payload); err != nil {
+		return nil, err
+	}
+	return payload.Reports, nil
+}
+
+// planFields are the fields queried for a schema plan.
+const planFields = `name status schema pullRequest url migration`
+
+// Plans fetches the declarative schema plans matching the input.
+func (c *Client) Plans(ctx context.Context, input PlansInput) ([]Plan, error) {
+	var (
+		payload struct {
+			Plans []Plan `json:"schemaPlans"`
+		}
+		query = `query schemaPlans($input: SchemaPlansInput!) {
+		schemaPlans(input: $input) { ` + planFields + ` }
+	}`
+		vars = struct {
+			Input PlansInput `json:"input"`
+		}{
+			Input: input,
+		}
+	)
+	if err := c.post(ctx, query, vars, &payload); err != nil {
+		return nil, err
+	}
+	return payload.Plans, nil
+}
+
+// ApprovePlan approves the schema plan with the given name.
+func (c *Client) ApprovePlan(ctx context.Context, name string) (*Plan, error) {
+	return c.reviewPlan(ctx, "approveSchemaPlan", name)
+}
+
+// RejectPlan rejects the schema plan with the given name.
+func (c *Client) RejectPlan(ctx context.Context, name string) (*Plan, error) {
+	return c.reviewPlan(ctx, "rejectSchemaPlan", name)
+}
+
+// reviewPlan runs the given review mutation on the schema plan.
+func (c *Client) reviewPlan(ctx context.Context, mutation, name string) (*Plan, error) {
+	var (
+		payload map[string]*Plan
+		query   = `mutation ` + mutation + `($name: String!) {
+		` + mutation + `(name: $name) { ` + planFields + ` }
+	}`
+		vars = struct {
+			Name string `json:"name"`
+		}{
+			Name: name,
+		}
+	)
+	if err := c.post(ctx, query, vars, &payload); err != nil {
+		return nil, err
+	}
+	if payload[mutation] == nil {
+		return nil, fmt.Errorf("schema plan %q: %w", name, ErrNotFound)
+	}
+	return payload[mutation], nil
 }
@@ -5,7 +5,9 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -30,3 +32,340 @@ func TestClient_ValidateToken(t *testing.T) {
 	err := client.ValidateToken(context.Background())
 	require.NoError(t, err)
 }
+
+func TestClient_Repos(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var input struct {
+			Variables ReposInput `json:"variables"`
+		}
+		err := json.NewDecoder(r.Body).Decode(&input)
+		require.NoError(t, err)
+		require.Equal(t, ReposInput{
+			First:  10,
+			After:  "cursor",
+			Filter: &ReposFilter{Type: DirectoryType, Driver: "MYSQL", Search: "app"},
+		}, input.Variables)
+		_, err = w.Write([]byte(`{"data":{"repos":{"repos":[{"title":"App","slug":"app","url":"atlas://app","type":"MIGRATION_DIRECTORY","driver":"MYSQL"}],"pageInfo":{"hasNextPage":true,"endCursor":"next"}}}}`))
+		require.NoError(t, err)
+	}))
+	defer srv.Close()
+	client := New(srv.URL, "atlas-secret-token")
+	page, err := client.Repos(context.Background(), ReposInput{
+		First:  10,
+		After:  "cursor",
+		Filter: &ReposFilter{Type: DirectoryType, Driver: "MYSQL", Search: "app"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []Repo{{Title: "App", Slug: "app", URL: "atlas://app", Type: DirectoryType, Driver: "MYSQL"}}, page.Repos)
+	require.Equal(t, PageInfo{HasNextPage: true, EndCursor: "next"}, page.PageInfo)
+}
+
+func TestClient_CreateRepo(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var input struct {
+			Query     string `json:"query"`
+			Variables struct {
+				Input CreateRepoInput `json:"input"`
+			} `json:"variables"`
+		}
+		err := json.NewDecoder(r.Body).Decode(&input)
+		require.NoError(t, err)
+		require.Contains(t, input.Query, "createRepo")
+		require.Equal(t, CreateRepoInput{Slug: "app", Type: SchemaType, Driver: "POSTGRESQL"}, input.Variables.Input)
+		_, err = w.Write([]byte(`{"data":{"createRepo":{"title":"app","slug":"app","url":"atlas://app","type":"SCHEMA","driver":"POSTGRESQL"}}}`))
+		require.NoError(t, err)
+	}))
+	defer srv.Close()
+	client := New(srv.URL, "atlas-secret-token")
+	repo, err := client.CreateRepo(context.Background(), CreateRepoInput{Slug: "app", Type: SchemaType, Driver: "POSTGRESQL"})
+	require.NoError(t, err)
+	require.Equal(t, &Repo{Title: "app", Slug: "app", URL: "atlas://app", Type: SchemaType, Driver: "POSTGRESQL"}, repo)
+}
+
+func TestClient_Retry(t *testing.T) {
+	var calls, failures int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls <= failures {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, err := w.Write([]byte(`{"data":{"repos":{"repos":[]}}}`))
+		require.NoError(t, err)
+	}))
+	defer srv.Close()
+	client := New(srv.URL, "atlas-secret-token", WithBackoff(time.Millisecond, 5*time.Millisecond))
+	calls, failures = 0, 2
+	_, err := client.Repos(context.Background(), ReposInput{})
+	require.NoError(t, err)
+	require.Equal(t, 3, calls)
+
+	// Retries are exhausted.
+	calls, failures = 0, 10
+	_, err = client.Repos(context.Background(), ReposInput{})
+	var se *StatusError
+	require.ErrorAs(t, err, &se)
+	require.Equal(t, http.StatusServiceUnavailable, se.StatusCode)
+	require.Equal(t, 1+defaultRetries, calls)
+
+	// Mutations are not retried on server errors.
+	calls, failures = 0, 10
+	_, err = client.CreateRepo(context.Background(), CreateRepoInput{Slug: "app"})
+	require.ErrorAs(t, err, &se)
+	require.Equal(t, 1, calls)
+
+	// Unless they are read-only.
+	calls, failures = 0, 2
+	require.NoError(t, client.ValidateToken(context.Background()))
+	require.Equal(t, 3, calls)
+}
+
+func TestClient_RetryAfter(t *testing.T) {
+	var (
+		calls      int
+		status     int
+		retryAfter string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.Header().Set("Retry-After", retryAfter)
+			w.WriteHeader(status)
+			return
+		}
+		_, err := w.Write([]byte(`{"data":{"repos":{"repos":[]}}}`))
+		require.NoError(t, err)
+	}))
+	defer srv.Close()
+	client := New(srv.URL, "atlas-secret-token", WithBackoff(time.Millisecond, 5*time.Millisecond))
+	calls, status, retryAfter = 0, http.StatusServiceUnavailable, "1"
+	start := time.Now()
+	_, err := client.Repos(context.Background(), ReposInput{})
+	require.NoError(t, err)
+	require.Equal(t, 2, calls)
+	require.GreaterOrEqual(t, time.Since(start), time.Second)
+
+	// Delays longer than maxRetryAfter are not waited.
+	calls, retryAfter = 0, "120"
+	_, err = client.Repos(context.Background(), ReposInput{})
+	var se *StatusError
+	require.ErrorAs(t, err, &se)
+	require.Equal(t, 2*time.Minute, se.RetryAfter)
+	require.EqualError(t, err, "unexpected status code: 503, retry after 2m0s: ")
+	require.Equal(t, 1, calls)
+
+	calls, status = 0, http.StatusTooManyRequests
+	_, err = client.Repos(context.Background(), ReposInput{})
+	var rl *RateLimitError
+	require.ErrorAs(t, err, &rl)
+	require.Equal(t, 2*time.Minute, rl.RetryAfter)
+	require.Equal(t, 1, calls)
+}
+
+func TestClient_RateLimit(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		_, err := w.Write([]byte(`{"data":{"validateToken":{"success":true}}}`))
+		require.NoError(t, err)
+	}))
+	defer srv.Close()
+	client := New(srv.URL, "atlas-secret-token", WithBackoff(time.Millisecond, 5*time.Millisecond))
+	start := time.Now()
+	require.NoError(t, client.ValidateToken(context.Background()))
+	require.Equal(t, 2, calls)
+	require.GreaterOrEqual(t, time.Since(start), time.Second)
+
+	// Rate limit is reported once retries are disabled.
+	calls = 0
+	client = New(srv.URL, "atlas-secret-token", WithRetries(0))
+	err := client.ValidateToken(context.Background())
+	var rl *RateLimitError
+	require.ErrorAs(t, err, &rl)
+	require.Equal(t, time.Second, rl.RetryAfter)
+}
+
+func TestClient_RetryCanceled(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+	client := New(srv.URL, "atlas-secret-token", WithBackoff(time.Minute, time.Minute))
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := client.Repos(ctx, ReposInput{})
+	require.Error(t, err)
+	require.Less(t, time.Since(start), 10*time.Second)
+}
+
+func TestClient_Errors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Header.Get("Authorization") {
+		case "Bearer invalid":
+			w.WriteHeader(http.StatusUnauthorized)
+		default:
+			_, err := w.Write([]byte(`{"errors":[{"message":"repo not found","path":["repos"]}]}`))
+			require.NoError(t, err)
+		}
+	}))
+	client := New(srv.URL, "invalid")
+	err := client.ValidateToken(context.Background())
+	var ae *AuthError
+	require.ErrorAs(t, err, &ae)
+	require.Equal(t, http.StatusUnauthorized, ae.StatusCode)
+
+	client = New(srv.URL, "atlas-secret-token")
+	_, err = client.Repos(context.Background(), ReposInput{})
+	var ge *GraphQLError
+	require.ErrorAs(t, err, &ge)
+	require.Len(t, ge.Errors, 1)
+	require.Equal(t, "repo not found", ge.Errors[0].Message)
+
+	srv.Close()
+	client = New(srv.URL, "atlas-secret-token", WithRetries(0))
+	_, err = client.Repos(context.Background(), ReposInput{})
+	var te *TransportError
+	require.ErrorAs(t, err, &te)
+}
+
+func TestClient_ErrorKinds(t *testing.T) {
+	for _, tt := range []struct {
+		status int
+		body   string
+		want   error
+	}{
+		{status: http.StatusUnauthorized, want: ErrUnauthenticated},
+		{status: http.StatusForbidden, want: ErrForbidden},
+		{status: http.StatusNotFound, want: ErrNotFound},
+		{status: http.StatusInternalServerError, want: ErrServer},
+		{status: http.StatusOK, body: `{"errors":[{"message":"invalid token","extensions":{"code":"UNAUTHENTICATED"}}]}`, want: ErrUnauthenticated},
+		{status: http.StatusOK, body: `{"errors":[{"message":"access denied","extensions":{"code":"FORBIDDEN"}}]}`, want: ErrForbidden},
+		{status: http.StatusOK, body: `{"errors":[{"message":"no such repo","extensions":{"code":"NOT_FOUND"}}]}`, want: ErrNotFound},
+		{status: http.StatusOK, body: `{"errors":[{"message":"oops","extensions":{"code":"INTERNAL_SERVER_ERROR"}}]}`, want: ErrServer},
+	} {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tt.status)
+			_, err := w.Write([]byte(tt.body))
+			require.NoError(t, err)
+		}))
+		err := New(srv.URL, "atlas-secret-token", WithRetries(0)).ValidateToken(context.Background())
+		srv.Close()
+		require.ErrorIs(t, err, tt.want, "status %d, body %s", tt.status, tt.body)
+		for _, other := range []error{ErrUnauthenticated, ErrForbidden, ErrNotFound, ErrNetwork, ErrServer} {
+			if other != tt.want {
+				require.NotErrorIs(t, err, other)
+			}
+		}
+	}
+	// Unreachable server.
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+	err := New(srv.URL, "atlas-secret-token", WithRetries(0)).ValidateToken(context.Background())
+	require.ErrorIs(t, err, ErrNetwork)
+}
+
+func TestClient_TokenInfo(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := w.Write([]byte(`{"data":{"tokenInfo":{"type":"BOT","name":"ci","org":"ariga","scopes":["MIGRATE_PUSH"],"repos":["app"]}}}`))
+		require.NoError(t, err)
+	}))
+	defer srv.Close()
+	info, err := New(srv.URL, "atlas-secret-token").TokenInfo(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, &TokenInfo{Type: BotToken, Name: "ci", Org: "ariga", Scopes: []string{ScopeMigratePush}, Repos: []string{"app"}}, info)
+	require.True(t, info.CanPush(Repo{Slug: "app", Type: DirectoryType}))
+	require.False(t, info.CanPush(Repo{Slug: "app", Type: SchemaType}))
+	require.False(t, info.CanPush(Repo{Slug: "other", Type: DirectoryType}))
+}
+
+func TestClient_Repo(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var input struct {
+			Variables struct {
+				Slug string `json:"slug"`
+			} `json:"variables"`
+		}
+		err := json.NewDecoder(r.Body).Decode(&input)
+		require.NoError(t, err)
+		if input.Variables.Slug != "app" {
+			_, err = w.Write([]byte(`{"data":{"repo":null}}`))
+		} else {
+			_, err = w.Write([]byte(`{"data":{"repo":{"title":"App","slug":"app","url":"atlas://app","type":"SCHEMA","driver":"MYSQL","link":"https://ariga.atlasgo.cloud/schemas/1"}}}`))
+		}
+		require.NoError(t, err)
+	}))
+	defer srv.Close()
+	client := New(srv.URL, "atlas-secret-token")
+	repo, err := client.Repo(context.Background(), "app")
+	require.NoError(t, err)
+	require.Equal(t, &Repo{Title: "App", Slug: "app", URL: "atlas://app", Type: SchemaType, Driver: "MYSQL", Link: "https://ariga.atlasgo.cloud/schemas/1"}, repo)
+	_, err = client.Repo(context.Background(), "unknown")
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestClient_Reports(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var input struct {
+			Variables struct {
+				Input ReportsInput `json:"input"`
+			} `json:"variables"`
+		}
+		err := json.NewDecoder(r.Body).Decode(&input)
+		require.NoError(t, err)
+		require.Equal(t, ReportsInput{Repo: "ariga/atlas", Commit: "sha"}, input.Variables.Input)
+		_, err = w.Write([]byte(`{"data":{"ciReports":[{"type":"LINT","status":"FAILED","url":"https://ariga.atlasgo.cloud/ci/1","diagnostics":[{"file":"1.sql","code":"DS102","message":"drop table","severity":"ERROR"}]}]}}`))
+		require.NoError(t, err)
+	}))
+	defer srv.Close()
+	reports, err := New(srv.URL, "atlas-secret-token").Reports(context.Background(), ReportsInput{Repo: "ariga/atlas", Commit: "sha"})
+	require.NoError(t, err)
+	require.Equal(t, []Report{{
+		Type:   LintReport,
+		Status: "FAILED",
+		URL:    "https://ariga.atlasgo.cloud/ci/1",
+		Diagnostics: []Diagnostic{
+			{File: "1.sql", Code: "DS102", Message: "drop table", Severity: SeverityError},
+		},
+	}}, reports)
+}
+
+func TestClient_Plans(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var input struct {
+			Query     string `json:"query"`
+			Variables struct {
+				Name  string     `json:"name"`
+				Input PlansInput `json:"input"`
+			} `json:"variables"`
+		}
+		err := json.NewDecoder(r.Body).Decode(&input)
+		require.NoError(t, err)
+		switch {
+		case strings.Contains(input.Query, "approveSchemaPlan"):
+			require.Equal(t, "add-users", input.Variables.Name)
+			_, err = w.Write([]byte(`{"data":{"approveSchemaPlan":{"name":"add-users","status":"APPROVED"}}}`))
+		case strings.Contains(input.Query, "rejectSchemaPlan"):
+			_, err = w.Write([]byte(`{"data":{"rejectSchemaPlan":null}}`))
+		default:
+			require.Equal(t, PlansInput{Repo: "ariga/atlas", PullRequest: 1}, input.Variables.Input)
+			_, err = w.Write([]byte(`{"data":{"schemaPlans":[{"name":"add-users","status":"PENDING","schema":"app","pullRequest":1,"url":"https://ariga.atlasgo.cloud/plans/1","migration":"CREATE TABLE users (id int);"}]}}`))
+		}
+		require.NoError(t, err)
+	}))
+	defer srv.Close()
+	client := New(srv.URL, "atlas-secret-token")
+	plans, err := client.Plans(context.Background(), PlansInput{Repo: "ariga/atlas", PullRequest: 1})
+	require.NoError(t, err)
+	require.Equal(t, []Plan{{Name: "add-users", Status: PlanPending, Schema: "app", PullRequest: 1, URL: "https://ariga.atlasgo.cloud/plans/1", Migration: "CREATE TABLE users (id int);"}}, plans)
+	plan, err := client.ApprovePlan(context.Background(), "add-users")
+	require.NoError(t, err)
+	require.Equal(t, PlanApproved, plan.Status)
+	_, err = client.RejectPlan(context.Background(), "unknown")
+	require.ErrorIs(t, err, ErrNotFound)
+}
@@ -0,0 +1,140 @@
+package cloudapi
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// Errors reported by the Atlas Cloud API, regardless of whether they were signaled by the
+// HTTP status code or by the code extension of a GraphQL error. Use errors.Is to check them.
+var (
+	ErrUnauthenticated = errors.New("unauthenticated")
+	ErrForbidden       = errors.New("forbidden")
+	ErrNotFound        = errors.New("not found")
+	ErrNetwork         = errors.New("network error")
+	ErrServer          = errors.New("server error")
+)
+
+type (
+	// AuthError is returned when the Atlas Cloud API rejects the token.
+	AuthError struct {
+		StatusCode int
+		Body       string
+	}
+	// RateLimitError is returned when the request is still rate-limited after all retries.
+	RateLimitError struct {
+		// RetryAfter is the delay requested by the server, if any.
+		RetryAfter time.Duration
+		Body       string
+	}
+	// GraphQLError is returned when the response contains GraphQL errors.
+	GraphQLError struct {
+		Errors gqlerror.List
+	}
+	// TransportError is returned when the Atlas Cloud API could not be reached.
+	TransportError struct {
+		Err error
+	}
+	// StatusError is returned for any other unexpected HTTP status code.
+	StatusError struct {
+		StatusCode int
+		// RetryAfter is the delay requested by the server with
+		// a 503 Service Unavailable response, if any.
+		RetryAfter time.Duration
+		Body       string
+	}
+)
+
+func (e *AuthError) Error() string {
+	return fmt.Sprintf("unauthorized: %d: %s", e.StatusCode, e.Body)
+}
+
+// Is reports whether the error matches ErrUnauthenticated or ErrForbidden.
+func (e *AuthError) Is(target error) bool {
+	return e.StatusCode == http.StatusUnauthorized && target == ErrUnauthenticated ||
+		e.StatusCode == http.StatusForbidden && target == ErrForbidden
+}
+
+func (e *RateLimitError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("rate limited, retry after %s: %s", e.RetryAfter, e.Body)
+	}
+	return fmt.Sprintf("rate limited: %s", e.Body)
+}
+
+func (e *GraphQLError) Error() string {
+	return e.Errors.Error()
+}
+
+// Unwrap returns the underlying GraphQL errors.
+func (e *GraphQLError) Unwrap() []error {
+	errs := make([]error, len(e.Errors))
+	for i := range e.Errors {
+		errs[i] = e.Errors[i]
+	}
+	return errs
+}
+
+// Is reports whether the code extension of one of the errors matches target.
+func (e *GraphQLError) Is(target error) bool {
+	for _, err := range e.Errors {
+		if code, ok := err.Extensions["code"].(string); ok && codeErrors[code] == target {
+			return true
+		}
+	}
+	return false
+}
+
+// codeErrors maps the code extension of GraphQL errors to their sentinel error.
+var codeErrors = map[string]error{
+	"UNAUTHENTICATED":       ErrUnauthenticated,
+	"UNAUTHORIZED":          ErrUnauthenticated,
+	"FORBIDDEN":             ErrForbidden,
+	"NOT_FOUND":             ErrNotFound,
+	"INTERNAL_SERVER_ERROR": ErrServer,
+}
+
+func (e *TransportError) Error() string {
+	return fmt.Sprintf("request failed: %v", e.Err)
+}
+
+// Unwrap returns the underlying transport error.
+func (e *TransportError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether the error matches ErrNetwork.
+func (e *TransportError) Is(target error) bool {
+	return target == ErrNetwork
+}
+
+func (e *StatusError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("unexpected status code: %d, retry after %s: %s", e.StatusCode, e.RetryAfter, e.Body)
+	}
+	return fmt.Sprintf("unexpected status code: %d: %s", e.StatusCode, e.Body)
+}
+
+// Is reports whether the error matches ErrNotFound or ErrServer.
+func (e *StatusError) Is(target error) bool {
+	return e.StatusCode == http.StatusNotFound && target == ErrNotFound ||
+		e.StatusCode >= http.StatusInternalServerError && target == ErrServer
+}
+
+// statusError maps a non-200 response to its typed error.
+func statusError(res *http.Response, body string) error {
+	switch res.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return &AuthError{StatusCode: res.StatusCode, Body: body}
+	case http.StatusTooManyRequests:
+		return &RateLimitError{RetryAfter: retryAfter(res), Body: body}
+	case http.StatusServiceUnavailable:
+		return &StatusError{StatusCode: res.StatusCode, RetryAfter: retryAfter(res), Body: body}
+	default:
+		return &StatusError{StatusCode: res.StatusCode, Body: body}
+	}
+}
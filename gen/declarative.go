@@ -0,0 +1,61 @@
+package gen
+
+// declarative returns the workflow of the declarative flow. It plans the schema
+// changes on pull requests, and applies the approved plan on the default branch.
+func declarative(c *Config) *Workflow {
+	w := c.workflow(&Event{Inline: true}, &Event{Branches: c.Branches(), Inline: true, Paths: c.paths()})
+	plan := c.job("plan")
+	plan.If = "${{ github.event_name == 'pull_request' }}"
+	if c.Triggers.MergeGroup {
+		plan.Comment = "Plan the changes on pull requests, and in the merge queue."
+		plan.If = "${{ github.event_name == 'pull_request' || github.event_name == 'merge_group' }}"
+	}
+	in := inputs{}.add("from", c.From)
+	if !c.Env.HasSchemaSrc {
+		in = in.add("to", c.To)
+	}
+	planStep := c.action("schema/plan", in.
+		schemaName(c).
+		devURL(c).
+		config(c))
+	plan.Steps = append(plan.Steps, planStep)
+	c.onChanges(plan, planStep)
+
+	apply := c.job("apply")
+	apply.If = "${{ github.event_name == 'push' && github.ref == 'refs/heads/" + c.DefaultBranch + "' }}"
+	approve := c.action("schema/plan/approve", c.fromTo("from").
+		devURL(c).
+		schemaName(c).
+		config(c))
+	approve.ID = "plan-approve"
+	in = inputs{}.devURL(c)
+	if !c.Env.HasSchemaSrc {
+		in = in.add("url", c.To)
+	}
+	apply.Steps = append(apply.Steps, approve, c.action("schema/push", in.
+		schemaName(c).
+		config(c)))
+	if c.SetupSchemaApply {
+		step := c.action("schema/apply", c.fromTo("url").
+			devURL(c).
+			config(c).
+			add("plan", "${{ steps.plan-approve.outputs.plan }}"))
+		step.If = "${{ steps.plan-approve.outputs.status == 'APPROVED' }}"
+		apply.Steps = append(apply.Steps, step)
+	}
+	w.Jobs = []*Job{plan, apply}
+	return w
+}
+
+// fromTo returns the inputs of the current and desired states, unless
+// set by the config file. The current state is set by the given input.
+func (c *Config) fromTo(from string) inputs {
+	var in inputs
+	if !c.Env.HasURL {
+		in = in.add(from, c.From)
+	}
+	if !c.Env.HasSchemaSrc {
+		in = in.add("to", c.To)
+	}
+	return in
+}
@@ -0,0 +1,119 @@
+package gen
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// encode writes the node in the layout of hand-written workflows: block mappings
+// and sequences indented by two spaces, flow sequences written as [ a, b ], and
+// folded scalars written one line per line of their value. yaml.v3 can write
+// neither of the last two, and does not allow limiting the line width.
+// The document does not end with a newline.
+func encode(n *yaml.Node) ([]byte, error) {
+	var b strings.Builder
+	if err := encodeMapping(&b, n, 0, false); err != nil {
+		return nil, err
+	}
+	return []byte(strings.TrimSuffix(b.String(), "\n")), nil
+}
+
+// encodeMapping writes the keys of the mapping at the indentation. If inline is
+// set, the first key is written on the current line, e.g. after a "- ".
+func encodeMapping(b *strings.Builder, n *yaml.Node, indent int, inline bool) error {
+	if n.Kind != yaml.MappingNode {
+		return fmt.Errorf("unexpected node kind %v, expected a mapping", n.Kind)
+	}
+	pad := strings.Repeat(" ", indent)
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		k, v := n.Content[i], n.Content[i+1]
+		if k.HeadComment != "" {
+			if i == 0 && inline {
+				return fmt.Errorf("unexpected comment on the first key %q of a sequence item", k.Value)
+			}
+			for _, l := range strings.Split(k.HeadComment, "\n") {
+				b.WriteString(pad + l + "\n")
+			}
+		}
+		if i > 0 || !inline {
+			b.WriteString(pad)
+		}
+		b.WriteString(k.Value + ":")
+		if err := encodeValue(b, v, indent); err != nil {
+			return fmt.Errorf("%s: %w", k.Value, err)
+		}
+	}
+	return nil
+}
+
+// encodeValue writes the value of a key written at the indentation.
+func encodeValue(b *strings.Builder, n *yaml.Node, indent int) error {
+	pad := strings.Repeat(" ", indent+2)
+	switch {
+	case n.Kind == yaml.ScalarNode && n.Tag == "!!null":
+		b.WriteString("\n")
+	case n.Kind == yaml.ScalarNode && n.Style == yaml.FoldedStyle:
+		b.WriteString(" >-\n")
+		for _, l := range strings.Split(n.Value, "\n") {
+			b.WriteString(pad + l + "\n")
+		}
+	case n.Kind == yaml.ScalarNode:
+		b.WriteString(" " + scalarText(n))
+		if n.LineComment != "" {
+			b.WriteString(" " + n.LineComment)
+		}
+		b.WriteString("\n")
+	case n.Kind == yaml.SequenceNode && n.Style == yaml.FlowStyle:
+		items := make([]string, len(n.Content))
+		for i, c := range n.Content {
+			if c.Kind != yaml.ScalarNode {
+				return fmt.Errorf("unexpected node kind %v in a flow sequence", c.Kind)
+			}
+			items[i] = scalarText(c)
+		}
+		b.WriteString(" [ " + strings.Join(items, ", ") + " ]\n")
+	case n.Kind == yaml.SequenceNode:
+		b.WriteString("\n")
+		for _, c := range n.Content {
+			b.WriteString(pad + "- ")
+			switch c.Kind {
+			case yaml.ScalarNode:
+				b.WriteString(scalarText(c) + "\n")
+			case yaml.MappingNode:
+				if err := encodeMapping(b, c, indent+4, true); err != nil {
+					return err
+				}
+			default:
+				return fmt.Errorf("unexpected node kind %v in a sequence", c.Kind)
+			}
+		}
+	case n.Kind == yaml.MappingNode:
+		b.WriteString("\n")
+		return encodeMapping(b, n, indent+2, false)
+	default:
+		return fmt.Errorf("unexpected node kind %v", n.Kind)
+	}
+	return nil
+}
+
+// scalarText returns the text of the scalar in its style. Strings without
+// a style are written as is, unless they would be read back differently.
+func scalarText(n *yaml.Node) string {
+	switch {
+	case n.Style == yaml.DoubleQuotedStyle, strings.Contains(n.Value, "\n"):
+		return strconv.Quote(n.Value)
+	case n.Style == yaml.SingleQuotedStyle, n.Tag == "!!str" && !plain(n.Value):
+		return "'" + strings.ReplaceAll(n.Value, "'", "''") + "'"
+	default:
+		return n.Value
+	}
+}
+
+// plain reports if s can be written without quotes, i.e. if it is read back as the same string.
+func plain(s string) bool {
+	var v any
+	return yaml.Unmarshal([]byte(s), &v) == nil && v == s
+}
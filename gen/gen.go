@@ -1,62 +1,480 @@
 package gen
 
 import (
-	"bytes"
-	"embed"
-	"text/template"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
 )
 
 type (
 	Env struct {
-		Name string
+		Name string `json:"name,omitempty"`
 		// HasDevURL is true if the env block has dev attribute
-		HasDevURL bool
+		HasDevURL bool `json:"hasDevURL,omitempty"`
 		// HasURL is true if the env block has url attribute
-		HasURL bool
+		HasURL bool `json:"hasURL,omitempty"`
 		// Path is the path of the file containing the env block
-		Path string
+		Path string `json:"path,omitempty"`
 		// HasSchemaSrc is true if the env block has schema.src attribute
-		HasSchemaSrc bool
+		HasSchemaSrc bool `json:"hasSchemaSrc,omitempty"`
 		// HasRepoName is true if the schema block has repo.name attribute
-		HasRepoName bool
+		HasRepoName bool `json:"hasRepoName,omitempty"`
+		// SchemaSrc holds the URLs of the schema.src attribute,
+		// if they can be evaluated without an evaluation context.
+		SchemaSrc []string `json:"schemaSrc,omitempty"`
 	}
-	// Config passed to template parser
+	// Config of the generated workflow.
 	Config struct {
-		Flow             string
-		From             string
-		To               string
-		Path             string
-		DirName          string
-		SecretName       string
-		DefaultBranch    string
-		Driver           string
-		Env              Env
-		SchemaScope      bool
-		CloudRepo        string
-		SetupSchemaApply bool
+		Flow             string `json:"flow"`
+		From             string `json:"from,omitempty"`
+		To               string `json:"to,omitempty"`
+		Path             string `json:"path,omitempty"`
+		DirName          string `json:"dirName,omitempty"`
+		SecretName       string `json:"secretName,omitempty"`
+		DefaultBranch    string `json:"defaultBranch"`
+		Driver           string `json:"driver,omitempty"`
+		Env              Env    `json:"env"`
+		SchemaScope      bool   `json:"schemaScope,omitempty"`
+		CloudRepo        string `json:"cloudRepo,omitempty"`
+		SetupSchemaApply bool   `json:"setupSchemaApply,omitempty"`
+		// Offline is true if the workflow runs without Atlas Cloud.
+		Offline bool `json:"offline,omitempty"`
+		// Pins maps the actions used by the workflow, in the
+		// <owner>/<repo>@<ref> format, to the commits they are pinned to.
+		Pins map[string]Pin `json:"pins,omitempty"`
+		// Mirrors maps the repositories of the actions, e.g. ariga/atlas-action,
+		// to their mirrors on GitHub Enterprise Server, e.g. github-actions/atlas-action.
+		Mirrors map[string]string `json:"mirrors,omitempty"`
+		// Triggers configures the events that run the workflow.
+		Triggers Triggers `json:"triggers"`
+		// RequiredCheck is true if the check of the workflow is required for merging.
+		// Pull requests must report it even if they do not change the filtered paths,
+		// so these are checked by a step of the job instead of filtering the event.
+		RequiredCheck bool `json:"requiredCheck,omitempty"`
+		// RunsOn are the labels of the runner, defaults to ubuntu-latest.
+		RunsOn []string `json:"runsOn,omitempty"`
+		// Container is the image of the container the jobs run in, if any.
+		Container string `json:"container,omitempty"`
+		// TimeoutMinutes is the maximum time the jobs can run, if set.
+		TimeoutMinutes int `json:"timeoutMinutes,omitempty"`
+		// DevDBMode is the way the dev-database is provided to the actions.
+		// If empty, it is chosen based on the runner.
+		DevDBMode string `json:"devDBMode,omitempty"`
+		// DevURLSecret is the name of the secret holding the dev-database URL
+		// in DevDBCustom mode.
+		DevURLSecret string `json:"devURLSecret,omitempty"`
+	}
+	// Triggers configures the events that run the workflow.
+	Triggers struct {
+		// Branches that run the workflow on push, in addition to the default branch.
+		Branches []string `json:"branches,omitempty"`
+		// MergeGroup runs the workflow on merge queue checks.
+		MergeGroup bool `json:"mergeGroup,omitempty"`
+		// Paths overrides the path filters derived from the config.
+		Paths []string `json:"paths,omitempty"`
+		// Dispatch allows running the workflow manually.
+		// The declarative workflow can always be run manually.
+		Dispatch bool `json:"dispatch,omitempty"`
+		// Inputs of the workflow_dispatch event, implies Dispatch.
+		Inputs []Input `json:"inputs,omitempty"`
+	}
+	// Input of the workflow_dispatch event. It is exposed to the steps
+	// as an environment variable, e.g. DRY_RUN for the dry-run input.
+	Input struct {
+		Name        string `json:"name"`
+		Description string `json:"description,omitempty"`
+		// Type of the input: string, boolean, number, choice or environment.
+		Type     string `json:"type"`
+		Default  string `json:"default,omitempty"`
+		Required bool   `json:"required,omitempty"`
+		// Options of a choice input.
+		Options []string `json:"options,omitempty"`
+	}
+	// Pin is the commit an action reference is pinned to.
+	Pin struct {
+		// SHA of the commit.
+		SHA string `json:"sha"`
+		// Version is the most specific tag of the commit, e.g. v3.6.0.
+		Version string `json:"version"`
 	}
 )
 
-var (
-	Drivers = []string{"MYSQL", "POSTGRESQL", "MARIADB", "SQLITE", "SQLSERVER", "CLICKHOUSE"}
-	//go:embed *.tmpl
-	files embed.FS
-	tmpl  = template.Must(template.New("atlas-sync-action").
-		ParseFS(files, "*.tmpl"))
-)
+// Drivers are the supported database drivers.
+var Drivers = []string{"MYSQL", "POSTGRESQL", "MARIADB", "SQLITE", "SQLSERVER", "CLICKHOUSE"}
 
 // Generate the content of the atlas ci lint yaml.
 func Generate(cfg *Config) ([]byte, error) {
-	b := bytes.NewBuffer(nil)
+	w, err := Build(cfg)
+	if err != nil {
+		return nil, err
+	}
+	b, err := Marshal(w)
+	if err != nil {
+		return nil, err
+	}
+	if err := Validate(b); err != nil {
+		return nil, fmt.Errorf("invalid generated workflow: %w", err)
+	}
+	return b, nil
+}
+
+// Build the workflow of the configured flow.
+func Build(cfg *Config) (*Workflow, error) {
+	var build func(*Config) *Workflow
 	switch cfg.Flow {
 	case "versioned":
-		if err := tmpl.ExecuteTemplate(b, "versioned.tmpl", cfg); err != nil {
-			return nil, err
+		build = versioned
+	case "declarative":
+		build = declarative
+	default:
+		return nil, fmt.Errorf("unknown flow %q", cfg.Flow)
+	}
+	// An empty name renders the token as ${{ secrets. }}.
+	if !cfg.Offline && cfg.SecretName == "" {
+		return nil, errors.New("the name of the Atlas Cloud token secret is required")
+	}
+	return build(cfg), nil
+}
+
+// workflow returns the workflow triggered by the push and pull_request events,
+// without its jobs. Changes to the workflow file itself run it on push.
+func (c *Config) workflow(push, pr *Event) *Workflow {
+	push.Branches = c.Branches()
+	if paths := c.paths(); len(paths) > 0 {
+		push.Paths = append([]any{".github/workflows/ci-atlas.yaml"}, paths...)
+	}
+	if c.RequiredCheck {
+		pr.Paths = nil
+	}
+	w := &Workflow{
+		Name: "Atlas",
+		On: Events{
+			Push:        push,
+			PullRequest: pr,
+			MergeGroup:  c.Triggers.MergeGroup,
+		},
+		Permissions: []Var{
+			{"contents", "read"},
+			{"pull-requests", "write"},
+		},
+		PermissionsComment: "Permissions to write comments on the pull request.",
+	}
+	if c.Dispatch() {
+		w.On.Dispatch = &Dispatch{Inputs: c.Triggers.Inputs}
+	}
+	return w
+}
+
+// paths returns the quoted path filters of the push and pull_request events.
+func (c *Config) paths() []any {
+	var paths []any
+	for _, p := range c.PathFilters() {
+		paths = append(paths, Quoted(p))
+	}
+	return paths
+}
+
+// onChanges adds a step to the job checking if the pull request changes the filtered
+// paths, and runs the step only if it does, or if the event is not a pull request.
+// It does nothing unless the check is required, as the event is filtered otherwise.
+func (c *Config) onChanges(j *Job, s *Step) {
+	paths := c.PathFilters()
+	if !c.RequiredCheck || len(paths) == 0 {
+		return
+	}
+	// The diff exits with 1 if there are changes, and with 128 on failure,
+	// in which case the step runs anyway.
+	changes := &Step{
+		Name: "Check for changes",
+		ID:   "changes",
+		If:   "github.event_name == 'pull_request'",
+		Env:  []Var{{"GIT_BASE", "origin/${{ github.base_ref }}"}},
+		Run:  []string{`git diff --quiet "$GIT_BASE"...HEAD --`},
+	}
+	for _, p := range paths {
+		changes.Run = append(changes.Run, shellQuote(":(glob)"+p))
+	}
+	changes.Run = append(changes.Run, `|| echo "changed=true" >> "$GITHUB_OUTPUT"`)
+	// Run the check right after the checkout.
+	j.Steps = append(j.Steps[:1], append([]*Step{changes}, j.Steps[1:]...)...)
+	s.If = "github.event_name != 'pull_request' || steps.changes.outputs.changed == 'true'"
+}
+
+// shellQuote returns s single-quoted for the shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// job returns a job that checks out the repository and sets up Atlas.
+func (c *Config) job(id string) *Job {
+	setup := c.step("ariga/setup-atlas@v0", nil)
+	if !c.Offline {
+		setup.With = inputs{}.add("cloud-token", "${{ secrets."+c.SecretName+" }}")
+	}
+	return &Job{
+		ID:             id,
+		Services:       c.services(),
+		RunsOn:         c.RunsOn,
+		Container:      c.Container,
+		TimeoutMinutes: c.TimeoutMinutes,
+		Env:            c.env(),
+		Steps: []*Step{
+			c.step("actions/checkout@v3", []Var{{"fetch-depth", 0}}),
+			setup,
+		},
+	}
+}
+
+// env returns the environment variables of the jobs. The inputs of manual
+// runs are exposed to the steps, e.g. to be read by getenv() in atlas.hcl.
+func (c *Config) env() []Var {
+	env := []Var{{"GITHUB_TOKEN", "${{ github.token }}"}}
+	for _, in := range c.Triggers.Inputs {
+		env = append(env, Var{in.Env(), "${{ inputs." + in.Name + " }}"})
+	}
+	return env
+}
+
+// Env returns the name of the environment variable holding the input.
+func (in Input) Env() string {
+	return strings.ToUpper(strings.ReplaceAll(in.Name, "-", "_"))
+}
+
+// step returns a step using the action with the given inputs.
+func (c *Config) step(action string, with []Var) *Step {
+	uses, version := c.uses(action)
+	return &Step{Uses: uses, Version: version, With: with}
+}
+
+// action returns a step using an action of ariga/atlas-action, e.g. migrate/lint.
+func (c *Config) action(name string, with inputs) *Step {
+	return c.step("ariga/atlas-action/"+name+"@v1", with)
+}
+
+// inputs of a step. Inputs with empty values are not added.
+type inputs []Var
+
+// add the input, if its value is not empty.
+func (in inputs) add(name, value string) inputs {
+	if value == "" {
+		return in
+	}
+	return append(in, Var{name, value})
+}
+
+// quoted adds the input as a Quoted value, if it is not empty.
+func (in inputs) quoted(name, value string) inputs {
+	if value == "" {
+		return in
+	}
+	return append(in, Var{name, Quoted(value)})
+}
+
+// devURL adds the URL of the dev-database, unless set by the config file.
+// URLs read from secrets are expressions, and are not quoted.
+func (in inputs) devURL(c *Config) inputs {
+	switch {
+	case c.Env.HasDevURL:
+		return in
+	case c.DevDB() == DevDBCustom:
+		return in.add("dev-url", c.DevURL())
+	default:
+		return in.quoted("dev-url", c.DevURL())
+	}
+}
+
+// schemaName adds the name of the schema in Atlas Cloud, unless set by the config file.
+func (in inputs) schemaName(c *Config) inputs {
+	if c.Env.HasRepoName {
+		return in
+	}
+	return in.add("schema-name", c.CloudRepo)
+}
+
+// config adds the config file and the env to use, if any.
+func (in inputs) config(c *Config) inputs {
+	if c.Env.Path != "" {
+		in = in.quoted("config", "file://"+c.Env.Path)
+	}
+	return in.quoted("env", c.Env.Name)
+}
+
+// Uses returns the reference of the action used by a workflow step, with its
+// repository replaced by its mirror, if set. If the action is pinned, the
+// reference is rendered as <owner>/<repo>[/<path>]@<sha> # <version>.
+func (c *Config) Uses(action string) string {
+	uses, version := c.uses(action)
+	if version == "" {
+		return uses
+	}
+	return uses + " # " + version
+}
+
+// uses returns the reference of the action, and the version it is pinned to, if any.
+func (c *Config) uses(action string) (string, string) {
+	repo, path, ref := SplitAction(action)
+	if m, ok := c.Mirrors[repo]; ok {
+		repo = m
+	}
+	pin, ok := c.Pins[repo+"@"+ref]
+	if !ok {
+		return repo + path + "@" + ref, ""
+	}
+	return repo + path + "@" + pin.SHA, pin.Version
+}
+
+// SplitAction splits an action reference, such as ariga/atlas-action/migrate/lint@v1,
+// into its repository (ariga/atlas-action), path (/migrate/lint) and ref (v1).
+func SplitAction(action string) (repo, path, ref string) {
+	action, ref, _ = strings.Cut(action, "@")
+	parts := strings.SplitN(action, "/", 3)
+	if len(parts) < 2 {
+		return action, "", ref
+	}
+	repo = parts[0] + "/" + parts[1]
+	if len(parts) == 3 {
+		path = "/" + parts[2]
+	}
+	return repo, path, ref
+}
+
+// Actions returns the sorted actions used by the generated workflow,
+// in the <owner>/<repo>@<ref> format used as keys of Config.Pins.
+func Actions(cfg *Config) ([]string, error) {
+	// Build the workflow without pins to get the original references.
+	c := *cfg
+	c.Pins = nil
+	w, err := Build(&c)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	for _, j := range w.Jobs {
+		for _, s := range j.Steps {
+			if s.Uses == "" {
+				continue
+			}
+			repo, _, ref := SplitAction(s.Uses)
+			seen[repo+"@"+ref] = true
+		}
+	}
+	actions := make([]string, 0, len(seen))
+	for a := range seen {
+		actions = append(actions, a)
+	}
+	sort.Strings(actions)
+	return actions, nil
+}
+
+// Ways of providing the dev-database to the actions.
+const (
+	// DevDBService runs the dev-database as a service container of the job.
+	DevDBService = "service"
+	// DevDBDocker lets Atlas spin up the dev-database using a docker:// URL.
+	DevDBDocker = "docker"
+	// DevDBCustom reads the dev-database URL from a secret.
+	DevDBCustom = "custom"
+)
+
+// DevDBModes are the supported ways of providing the dev-database.
+var DevDBModes = []string{DevDBService, DevDBDocker, DevDBCustom}
+
+// DevDB returns how the dev-database is provided to the actions. Unless set explicitly,
+// service containers are used by jobs on Linux runners, as GitHub supports them only on
+// Linux. Jobs running in a container always run on Linux, and cannot use docker:// URLs
+// as they have no Docker daemon. Jobs on other runners fall back to docker:// URLs.
+func (c *Config) DevDB() string {
+	if c.DevDBMode != "" {
+		return c.DevDBMode
+	}
+	if c.Container == "" && !linuxRunner(c.RunsOn) {
+		return DevDBDocker
+	}
+	return DevDBService
+}
+
+// linuxRunner reports whether the runner labels select a Linux runner.
+func linuxRunner(labels []string) bool {
+	if len(labels) == 0 {
+		return true
+	}
+	for _, l := range labels {
+		if l := strings.ToLower(l); l == "linux" || strings.HasPrefix(l, "ubuntu-") {
+			return true
 		}
+	}
+	return false
+}
+
+// CheckName returns the name of the check the workflow reports on pull requests.
+func (c *Config) CheckName() string {
+	if c.Flow == "declarative" {
+		return "plan"
+	}
+	return "atlas"
+}
+
+// Branches returns the branches that run the workflow on push.
+func (c *Config) Branches() []string {
+	return append([]string{c.DefaultBranch}, c.Triggers.Branches...)
+}
+
+// Dispatch reports if the workflow can be run manually.
+func (c *Config) Dispatch() bool {
+	return c.Flow == "declarative" || c.Triggers.Dispatch || len(c.Triggers.Inputs) > 0
+}
+
+// PathFilters returns the path filters of the push and pull_request events.
+// Unless set explicitly, they are derived from the migration directory, the
+// schema files and the atlas.hcl file. An empty list means no filtering.
+func (c *Config) PathFilters() []string {
+	if len(c.Triggers.Paths) > 0 {
+		return c.Triggers.Paths
+	}
+	var paths []string
+	switch c.Flow {
+	case "versioned":
+		paths = append(paths, path.Join(c.Path, "**"))
 	case "declarative":
-		if err := tmpl.ExecuteTemplate(b, "declarative.tmpl", cfg); err != nil {
-			return nil, err
+		var srcs []string
+		switch {
+		case c.Env.HasSchemaSrc && len(c.Env.SchemaSrc) == 0:
+			// The schema source is unknown.
+			return nil
+		case c.Env.HasSchemaSrc:
+			// Sources in the config file are relative to it.
+			for _, s := range c.Env.SchemaSrc {
+				p, ok := filePath(s)
+				if !ok {
+					return nil
+				}
+				srcs = append(srcs, path.Join(path.Dir(c.Env.Path), p))
+			}
+		default:
+			p, ok := filePath(c.To)
+			if !ok {
+				return nil
+			}
+			srcs = append(srcs, path.Clean(p))
+		}
+		for _, s := range srcs {
+			// A path without an extension is assumed to be a directory.
+			if path.Ext(s) == "" {
+				s = path.Join(s, "**")
+			}
+			paths = append(paths, s)
 		}
 	}
-	return b.Bytes(), nil
+	if c.Env.Path != "" {
+		paths = append(paths, c.Env.Path)
+	}
+	return paths
+}
+
+// filePath returns the path of a file:// URL.
+func filePath(u string) (string, bool) {
+	p, ok := strings.CutPrefix(u, "file://")
+	return p, ok && p != ""
 }
@@ -9,41 +9,212 @@ import (
 )
 
 func TestVersionedFlowGen(t *testing.T) {
-	dir, err := os.ReadDir("testdata/versioned")
-	require.NoError(t, err)
-	for _, f := range dir {
-		t.Run(f.Name(), func(t *testing.T) {
-			expected, err := os.ReadFile("testdata/versioned/" + f.Name())
+	// base returns the config the test cases start from.
+	base := func(driver string) *Config {
+		return &Config{
+			Flow:          "versioned",
+			Path:          "migrations",
+			DirName:       "name",
+			DefaultBranch: "master",
+			SecretName:    "ATLAS_CLOUD_TOKEN",
+			Driver:        driver,
+		}
+	}
+	type testCase struct {
+		filename string
+		config   *Config
+	}
+	testCases := []testCase{
+		{
+			filename: "postgresql_atlas_config.yml",
+			config: func() *Config {
+				c := base("POSTGRESQL")
+				c.Env = Env{Name: "dev", Path: "atlas.hcl", HasDevURL: true}
+				return c
+			}(),
+		},
+		{
+			filename: "mysql_schema_scope.yml",
+			config: func() *Config {
+				c := base("MYSQL")
+				c.SchemaScope = true
+				return c
+			}(),
+		},
+		{
+			filename: "postgresql_schema_scope.yml",
+			config: func() *Config {
+				c := base("POSTGRESQL")
+				c.SchemaScope = true
+				return c
+			}(),
+		},
+		{
+			filename: "mysql_offline.yml",
+			config: func() *Config {
+				c := base("MYSQL")
+				c.DirName, c.SecretName, c.Offline = "", "", true
+				return c
+			}(),
+		},
+		{
+			filename: "postgresql_offline_custom.yml",
+			config: func() *Config {
+				c := base("POSTGRESQL")
+				c.DirName, c.SecretName, c.Offline = "", "", true
+				c.DevDBMode, c.DevURLSecret = DevDBCustom, "ATLAS_DEV_URL"
+				c.Env = Env{Name: "ci", Path: "atlas.hcl"}
+				return c
+			}(),
+		},
+		{
+			filename: "mysql_pinned.yml",
+			config: func() *Config {
+				c := base("MYSQL")
+				c.Pins = map[string]Pin{
+					"actions/checkout@v3":   {SHA: "f43a0e5ff2bd294095638e18286ca9a3d1956744", Version: "v3.6.0"},
+					"ariga/setup-atlas@v0":  {SHA: "1dd2d4b2e9c0e2b7ae0d3e3b4f8a8e5b0f8c7d6a", Version: "v0.2.0"},
+					"ariga/atlas-action@v1": {SHA: "7b8cf10d4e4a01d4992d18a89f4d7dc5a3e6d6f4", Version: "v1.5.2"},
+				}
+				return c
+			}(),
+		},
+		{
+			filename: "mysql_triggers.yml",
+			config: func() *Config {
+				c := base("MYSQL")
+				c.Triggers = Triggers{
+					Branches:   []string{"release", "hotfix/*"},
+					MergeGroup: true,
+					Inputs: []Input{
+						{Name: "reason", Description: "Why it's run manually", Required: true},
+						{Name: "level", Type: "choice", Default: "warning", Options: []string{"warning", "error"}},
+					},
+				}
+				return c
+			}(),
+		},
+		{
+			filename: "mysql_paths.yml",
+			config: func() *Config {
+				c := base("MYSQL")
+				c.Triggers = Triggers{
+					Paths:    []string{"db/**", "!db/README.md"},
+					Dispatch: true,
+				}
+				return c
+			}(),
+		},
+		{
+			filename: "mysql_runner.yml",
+			config: func() *Config {
+				c := base("MYSQL")
+				c.RunsOn = []string{"self-hosted", "windows"}
+				return c
+			}(),
+		},
+		{
+			filename: "mysql_required_check.yml",
+			config: func() *Config {
+				c := base("MYSQL")
+				c.RequiredCheck = true
+				return c
+			}(),
+		},
+		{
+			filename: "postgresql_container.yml",
+			config: func() *Config {
+				c := base("POSTGRESQL")
+				c.RunsOn = []string{"self-hosted-linux"}
+				c.Container = "node:20"
+				c.TimeoutMinutes = 15
+				return c
+			}(),
+		},
+	}
+	// Each driver has a case per way of providing the dev-database.
+	for _, d := range []string{"MYSQL", "POSTGRESQL", "MARIADB", "SQLITE", "SQLSERVER", "CLICKHOUSE", "SPANNER"} {
+		name := strings.ToLower(d)
+		docker, custom := base(d), base(d)
+		docker.DevDBMode = DevDBDocker
+		custom.DevDBMode, custom.DevURLSecret = DevDBCustom, "ATLAS_DEV_URL"
+		testCases = append(testCases,
+			testCase{filename: name + ".yml", config: base(d)},
+			testCase{filename: name + "_docker.yml", config: docker},
+			testCase{filename: name + "_custom.yml", config: custom},
+		)
+	}
+	tested := make(map[string]bool)
+	for _, tc := range testCases {
+		tested[tc.filename] = true
+		t.Run(tc.filename, func(t *testing.T) {
+			expected, err := os.ReadFile("testdata/versioned/" + tc.filename)
 			require.NoError(t, err)
-			name := strings.TrimSuffix(f.Name(), ".yml")
-			cfg := &Config{
-				Flow:          "versioned",
-				Path:          "migrations",
-				DirName:       "name",
-				DefaultBranch: "master",
-				SecretName:    "ATLAS_CLOUD_TOKEN",
-				Driver:        name,
-				SchemaScope:   false,
-				Env: Env{
-					HasDevURL: false,
-				},
-			}
-			if strings.Contains(name, "atlas_config") {
-				cfg.Driver = strings.Split(name, "_")[0]
-				cfg.Env.Path = "atlas.hcl"
-				cfg.Env.Name = "dev"
-				cfg.Env.HasDevURL = true
-			}
-			if strings.Contains(name, "schema_scope") {
-				cfg.Driver = strings.Split(name, "_")[0]
-				cfg.SchemaScope = true
-			}
-			cfg.Driver = strings.ToUpper(cfg.Driver)
-			actual, err := Generate(cfg)
+			require.NoError(t, Validate(expected))
+			actual, err := Generate(tc.config)
 			require.NoError(t, err)
-			require.Equal(t, strings.TrimSpace(string(expected)), strings.TrimSpace(string(actual)))
+			require.Equal(t, string(expected), string(actual))
 		})
 	}
+	dir, err := os.ReadDir("testdata/versioned")
+	require.NoError(t, err)
+	for _, f := range dir {
+		require.True(t, tested[f.Name()], "no test case for testdata/versioned/%s", f.Name())
+	}
+}
+
+func TestActions(t *testing.T) {
+	cfg := &Config{
+		Flow:          "versioned",
+		Path:          "migrations",
+		DirName:       "name",
+		DefaultBranch: "master",
+		SecretName:    "ATLAS_CLOUD_TOKEN",
+		Driver:        "MYSQL",
+		Pins:          map[string]Pin{"actions/checkout@v3": {SHA: "sha", Version: "v3.6.0"}},
+	}
+	actions, err := Actions(cfg)
+	require.NoError(t, err)
+	require.Equal(t, []string{"actions/checkout@v3", "ariga/atlas-action@v1", "ariga/setup-atlas@v0"}, actions)
+}
+
+func TestDevDB(t *testing.T) {
+	for _, tt := range []struct {
+		cfg  Config
+		want string
+	}{
+		{Config{}, DevDBService},
+		{Config{RunsOn: []string{"ubuntu-22.04"}}, DevDBService},
+		{Config{RunsOn: []string{"self-hosted", "Linux", "x64"}}, DevDBService},
+		{Config{RunsOn: []string{"macos-latest"}}, DevDBDocker},
+		{Config{RunsOn: []string{"self-hosted", "windows"}}, DevDBDocker},
+		{Config{Container: "golang:1.21"}, DevDBService},
+		{Config{Container: "golang:1.21", DevDBMode: DevDBDocker}, DevDBDocker},
+		{Config{DevDBMode: DevDBCustom}, DevDBCustom},
+	} {
+		require.Equal(t, tt.want, tt.cfg.DevDB(), "%+v", tt.cfg)
+	}
+}
+
+func TestUses(t *testing.T) {
+	cfg := &Config{}
+	require.Equal(t, "ariga/atlas-action/migrate/lint@v1", cfg.Uses("ariga/atlas-action/migrate/lint@v1"))
+	cfg.Mirrors = map[string]string{"ariga/atlas-action": "mirrors/atlas-action"}
+	require.Equal(t, "mirrors/atlas-action/migrate/lint@v1", cfg.Uses("ariga/atlas-action/migrate/lint@v1"))
+	require.Equal(t, "actions/checkout@v3", cfg.Uses("actions/checkout@v3"))
+	cfg.Pins = map[string]Pin{"mirrors/atlas-action@v1": {SHA: "sha", Version: "v1.2.3"}}
+	require.Equal(t, "mirrors/atlas-action/migrate/lint@sha # v1.2.3", cfg.Uses("ariga/atlas-action/migrate/lint@v1"))
+}
+
+func TestSplitAction(t *testing.T) {
+	repo, path, ref := SplitAction("ariga/atlas-action/migrate/lint@v1")
+	require.Equal(t, "ariga/atlas-action", repo)
+	require.Equal(t, "/migrate/lint", path)
+	require.Equal(t, "v1", ref)
+	repo, path, ref = SplitAction("actions/checkout@v3")
+	require.Equal(t, "actions/checkout", repo)
+	require.Empty(t, path)
+	require.Equal(t, "v3", ref)
 }
 
 func TestDeclarativeFlowGen(t *testing.T) {
@@ -107,15 +278,104 @@ func TestDeclarativeFlowGen(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:     "file schema triggers",
+			filename: "plan_triggers.yml",
+			config: &Config{
+				Flow:          "declarative",
+				DefaultBranch: "master",
+				SecretName:    "ATLAS_CLOUD_TOKEN_X1",
+				Driver:        "MYSQL",
+				From:          "atlas://myrepo",
+				To:            "file://schema.hcl",
+				CloudRepo:     "myrepo",
+				Triggers: Triggers{
+					Branches:   []string{"release"},
+					MergeGroup: true,
+					Inputs:     []Input{{Name: "dry-run", Type: "boolean", Default: "true"}},
+				},
+			},
+		},
+		{
+			name:     "required check",
+			filename: "plan_required_check.yml",
+			config: &Config{
+				Flow:          "declarative",
+				DefaultBranch: "master",
+				SecretName:    "ATLAS_CLOUD_TOKEN_X1",
+				Driver:        "MYSQL",
+				From:          "atlas://myrepo",
+				To:            "file://schema.hcl",
+				CloudRepo:     "myrepo",
+				RequiredCheck: true,
+			},
+		},
+		{
+			name:     "docker dev database",
+			filename: "plan_docker.yml",
+			config: &Config{
+				Flow:          "declarative",
+				DefaultBranch: "master",
+				SecretName:    "ATLAS_CLOUD_TOKEN_X1",
+				Driver:        "POSTGRESQL",
+				SchemaScope:   true,
+				From:          "atlas://myrepo",
+				To:            "atlas://myrepo",
+				CloudRepo:     "myrepo",
+				DevDBMode:     DevDBDocker,
+			},
+		},
+		{
+			name:     "schema src paths",
+			filename: "plan_schema_src.yml",
+			config: &Config{
+				Flow:          "declarative",
+				DefaultBranch: "master",
+				SecretName:    "ATLAS_CLOUD_TOKEN_X1",
+				Driver:        "MYSQL",
+				CloudRepo:     "myrepo",
+				Env: Env{
+					Name:         "prod",
+					Path:         "db/atlas.hcl",
+					HasDevURL:    true,
+					HasURL:       true,
+					HasSchemaSrc: true,
+					HasRepoName:  true,
+					SchemaSrc:    []string{"file://schema", "file://../base.sql"},
+				},
+			},
+		},
+		{
+			name:     "unknown schema src",
+			filename: "plan_has_file_config.yml",
+			config: &Config{
+				Flow:             "declarative",
+				DefaultBranch:    "master",
+				SecretName:       "ATLAS_CLOUD_TOKEN_X1",
+				Driver:           "MYSQL",
+				CloudRepo:        "myrepo",
+				SetupSchemaApply: true,
+				Env: Env{
+					Name:         "prod",
+					Path:         "atlas.hcl",
+					HasDevURL:    true,
+					HasURL:       true,
+					HasSchemaSrc: true,
+					HasRepoName:  true,
+					SchemaSrc:    []string{"file://schema.hcl", "ent://ent/schema"},
+				},
+			},
+		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			expected, err := os.ReadFile("testdata/declarative/" + tc.filename)
 			require.NoError(t, err)
+			require.NoError(t, Validate(expected))
 			actual, err := Generate(tc.config)
 			require.NoError(t, err)
-			require.Equal(t, strings.TrimSpace(string(expected)), strings.TrimSpace(string(actual)))
+			require.Equal(t, string(expected), string(actual))
 		})
 	}
 }
@@ -0,0 +1,150 @@
+package gen
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	"gopkg.in/yaml.v3"
+)
+
+type (
+	// workflow is the subset of a GitHub Actions workflow needed
+	// for checking the inputs of the atlas-action steps.
+	workflow struct {
+		Jobs map[string]struct {
+			Steps []struct {
+				Uses string         `yaml:"uses"`
+				With map[string]any `yaml:"with"`
+			} `yaml:"steps"`
+		} `yaml:"jobs"`
+	}
+)
+
+var (
+	// schemaJSON is the GitHub Actions workflow schema, taken from SchemaStore:
+	// https://json.schemastore.org/github-workflow.json
+	//go:embed schema/github-workflow.json
+	schemaJSON string
+	schema     = jsonschema.MustCompileString("github-workflow.json", schemaJSON)
+	// requiredInputs maps the atlas-action actions to the inputs they require.
+	// Each entry lists alternatives, one of which must be set. The config input
+	// stands in for the URLs, as they can be set by the env block instead.
+	requiredInputs = map[string][][]string{
+		"migrate/lint":        {{"dir"}, {"dir-name"}, {"dev-url", "config"}},
+		"migrate/push":        {{"dir"}, {"dir-name"}, {"dev-url", "config"}},
+		"migrate/apply":       {{"dir"}, {"url", "config"}},
+		"schema/plan":         {{"to", "config"}, {"dev-url", "config"}},
+		"schema/plan/approve": {{"from", "config"}, {"to", "config"}},
+		"schema/push":         {{"url", "config"}, {"dev-url", "config"}},
+		"schema/apply":        {{"url", "config"}, {"to", "config"}, {"plan"}},
+	}
+)
+
+// Validate parses the workflow as YAML, validates it against the GitHub Actions
+// workflow schema and checks that each atlas-action step has the inputs it requires.
+func Validate(content []byte) error {
+	var doc any
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return fmt.Errorf("parsing workflow: %w", err)
+	}
+	// The schema validator works on JSON values.
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("parsing workflow: %w", err)
+	}
+	var v any
+	if err := json.Unmarshal(b, &v); err != nil {
+		return fmt.Errorf("parsing workflow: %w", err)
+	}
+	if err := schema.Validate(v); err != nil {
+		var verr *jsonschema.ValidationError
+		if !errors.As(err, &verr) {
+			return err
+		}
+		return fmt.Errorf("workflow does not match the GitHub Actions schema: %s", schemaError(verr))
+	}
+	var w workflow
+	if err := yaml.Unmarshal(content, &w); err != nil {
+		return fmt.Errorf("parsing workflow: %w", err)
+	}
+	jobs := make([]string, 0, len(w.Jobs))
+	for name := range w.Jobs {
+		jobs = append(jobs, name)
+	}
+	sort.Strings(jobs)
+	for _, job := range jobs {
+		for i, s := range w.Jobs[job].Steps {
+			repo, path, _ := SplitAction(s.Uses)
+			// Mirrors keep the name of the repository, but not its owner.
+			if !strings.HasSuffix(repo, "/atlas-action") {
+				continue
+			}
+			path = strings.TrimPrefix(path, "/")
+			required, ok := requiredInputs[path]
+			if !ok {
+				return fmt.Errorf("job %q, step %d: unknown atlas-action action %q", job, i+1, path)
+			}
+			for _, inputs := range required {
+				if !hasInput(s.With, inputs) {
+					return fmt.Errorf("job %q, step %d: atlas-action %q requires the %s input", job, i+1, path, strings.Join(quoteAll(inputs), " or "))
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// hasInput reports if one of the inputs is set.
+func hasInput(with map[string]any, inputs []string) bool {
+	for _, in := range inputs {
+		if v, ok := with[in]; ok && v != nil && v != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// schemaError returns the messages of the deepest validation errors. The schema
+// has many alternatives (oneOf/anyOf), and the errors of the other branches are
+// usually noise, e.g. "/on: expected string" when "/on/push/branches/0" is wrong.
+func schemaError(err *jsonschema.ValidationError) string {
+	var (
+		msgs  []string
+		depth = -1
+		walk  func(*jsonschema.ValidationError)
+	)
+	walk = func(e *jsonschema.ValidationError) {
+		if len(e.Causes) == 0 {
+			switch d := strings.Count(e.InstanceLocation, "/"); {
+			case d > depth:
+				depth, msgs = d, nil
+				fallthrough
+			case d == depth:
+				loc := e.InstanceLocation
+				if loc == "" {
+					loc = "/"
+				}
+				msgs = append(msgs, fmt.Sprintf("%s: %s", loc, e.Message))
+			}
+		}
+		for _, c := range e.Causes {
+			walk(c)
+		}
+	}
+	walk(err)
+	return strings.Join(msgs, "; ")
+}
+
+// quoteAll returns the strings quoted.
+func quoteAll(s []string) []string {
+	q := make([]string, len(s))
+	for i := range s {
+		q[i] = fmt.Sprintf("%q", s[i])
+	}
+	return q
+}
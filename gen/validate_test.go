@@ -0,0 +1,116 @@
+package gen
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		workflow string
+		wantErr  string
+	}{
+		{
+			name: "valid",
+			workflow: `
+name: Atlas
+on: [push]
+jobs:
+  atlas:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@v3
+      - uses: mirrors/atlas-action/migrate/lint@0123456789abcdef # v1.2.3
+        with:
+          dir: 'file://migrations'
+          dir-name: name
+          config: 'file://atlas.hcl'
+`,
+		},
+		{
+			name:     "yaml",
+			workflow: "on: [push\njobs:",
+			wantErr:  "parsing workflow:",
+		},
+		{
+			name: "schema",
+			workflow: `
+on:
+  push:
+    branches:
+      -
+jobs:
+  atlas:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@v3
+`,
+			wantErr: "workflow does not match the GitHub Actions schema: /on/push/branches/0: expected string, but got null",
+		},
+		{
+			name: "missing jobs",
+			workflow: `
+on: [push]
+`,
+			wantErr: `missing properties: 'jobs'`,
+		},
+		{
+			name: "missing input",
+			workflow: `
+on: [push]
+jobs:
+  atlas:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: ariga/atlas-action/migrate/apply@v1
+        with:
+          dir: 'file://migrations'
+`,
+			wantErr: `job "atlas", step 1: atlas-action "migrate/apply" requires the "url" or "config" input`,
+		},
+		{
+			name: "lint without dir-name",
+			workflow: `
+on: [push]
+jobs:
+  atlas:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: ariga/atlas-action/migrate/lint@v1
+        with:
+          dir: 'file://migrations'
+          dev-url: 'sqlite://dev?mode=memory'
+`,
+			wantErr: `job "atlas", step 1: atlas-action "migrate/lint" requires the "dir-name" input`,
+		},
+		{
+			name: "unknown action",
+			workflow: `
+on: [push]
+jobs:
+  atlas:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: ariga/atlas-action/migrate/unknown@v1
+`,
+			wantErr: `job "atlas", step 1: unknown atlas-action action "migrate/unknown"`,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate([]byte(tt.workflow))
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestGenerate_Invalid(t *testing.T) {
+	// Cloud workflows lint and push the directory, which requires its name.
+	_, err := Generate(&Config{Flow: "versioned", Path: "migrations", DefaultBranch: "master", SecretName: "ATLAS_CLOUD_TOKEN", Driver: "MYSQL"})
+	require.EqualError(t, err, `invalid generated workflow: job "atlas", step 3: atlas-action "migrate/lint" requires the "dir-name" input`)
+}
@@ -0,0 +1,73 @@
+package gen
+
+// versioned returns the workflow of the versioned flow. It lints the migration
+// directory on pull requests, and pushes and applies it on the default branch.
+// Offline workflows only lint the directory.
+func versioned(c *Config) *Workflow {
+	w := c.workflow(&Event{}, &Event{Paths: c.paths()})
+	job := c.job("atlas")
+	w.Jobs = []*Job{job}
+	if c.Offline {
+		lint := c.lint()
+		job.Steps = append(job.Steps, lint)
+		c.onChanges(job, lint)
+		return w
+	}
+	onPush := c.onDefaultPush()
+	lint := c.action("migrate/lint", c.dirInputs())
+	push := c.action("migrate/push", c.dirInputs())
+	push.If = onPush
+	job.Steps = append(job.Steps, lint, push)
+	c.onChanges(job, lint)
+	apply := c.action("migrate/apply", inputs{}.
+		quoted("dir", "file://"+c.Path).
+		add("url", "${{ secrets.DB_URL }}").
+		config(c))
+	apply.If = onPush
+	job.Steps = append(job.Steps, apply)
+	return w
+}
+
+// lint returns the step linting the migration directory of offline workflows.
+// The migrate/lint action reports to Atlas Cloud and requires the dir-name input,
+// so the step runs the CLI against the base branch of the pull request instead.
+// Expressions are passed as environment variables, and not expanded in the command.
+func (c *Config) lint() *Step {
+	s := &Step{
+		Name: "Lint migrations",
+		Env:  []Var{{"GIT_BASE", "origin/${{ github.base_ref || '" + c.DefaultBranch + "' }}"}},
+		Run:  []string{"atlas migrate lint", "--dir " + shellQuote("file://"+c.Path)},
+	}
+	switch {
+	case c.Env.HasDevURL:
+	case c.DevDB() == DevDBCustom:
+		s.Env = append(s.Env, Var{"DEV_URL", c.DevURL()})
+		s.Run = append(s.Run, `--dev-url "$DEV_URL"`)
+	default:
+		s.Run = append(s.Run, "--dev-url "+shellQuote(c.DevURL()))
+	}
+	if c.Env.Path != "" {
+		s.Run = append(s.Run, "--config "+shellQuote("file://"+c.Env.Path))
+	}
+	if c.Env.Name != "" {
+		s.Run = append(s.Run, "--env "+shellQuote(c.Env.Name))
+	}
+	s.Run = append(s.Run, `--git-base "$GIT_BASE"`)
+	return s
+}
+
+// dirInputs returns the inputs of the steps reading the migration directory.
+func (c *Config) dirInputs() inputs {
+	return inputs{}.
+		quoted("dir", "file://"+c.Path).
+		quoted("dir-name", c.DirName).
+		devURL(c).
+		config(c)
+}
+
+// onDefaultPush is the condition of the steps running on pushes to the default branch.
+// The event is checked too, as merge queue checks and manual runs on the default
+// branch have the same ref, and must not push or apply the migrations.
+func (c *Config) onDefaultPush() string {
+	return "github.event_name == 'push' && github.ref == 'refs/heads/" + c.DefaultBranch + "'"
+}
@@ -0,0 +1,418 @@
+package gen
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+type (
+	// Workflow is a GitHub Actions workflow. Its fields are marshaled
+	// in the order they are defined, and lists keep their order.
+	Workflow struct {
+		Name string
+		On   Events
+		// Permissions of the GITHUB_TOKEN, and the comment explaining them.
+		Permissions        []Var
+		PermissionsComment string
+		Jobs               []*Job
+	}
+	// Events that run the workflow.
+	Events struct {
+		Push        *Event
+		PullRequest *Event
+		MergeGroup  bool
+		// Dispatch is set if the workflow can be run manually.
+		Dispatch *Dispatch
+	}
+	// Event is a push or pull_request event, filtered by branches and paths.
+	Event struct {
+		Branches []string
+		// Inline writes the branches on one line, e.g. [ main ].
+		Inline bool
+		// Paths are strings or Quoted values.
+		Paths []any
+	}
+	// Dispatch is the workflow_dispatch event.
+	Dispatch struct {
+		Inputs []Input
+	}
+	// Job of the workflow.
+	Job struct {
+		ID string
+		// Comment explains the condition of the job, and is written above it.
+		Comment        string
+		If             string
+		Services       []*Service
+		RunsOn         []string
+		Container      string
+		TimeoutMinutes int
+		Env            []Var
+		Steps          []*Step
+	}
+	// Service is a container running alongside a job.
+	Service struct {
+		ID string
+		// Comment is written above the service.
+		Comment string
+		Image   string
+		Env     []Var
+		// Ports are strings or DoubleQuoted values.
+		Ports []any
+		// Options are the docker create options of the container,
+		// written one per line.
+		Options []string
+	}
+	// Step of a job running an action, or a command if Run is set.
+	Step struct {
+		Name string
+		// Uses is the reference of the action, and Version is the version it is
+		// pinned to, if any. The version is written as a comment after the reference.
+		Uses    string
+		Version string
+		ID      string
+		If      string
+		Env     []Var
+		With    []Var
+		// Run is the command of the step, written one argument per line.
+		Run []string
+	}
+	// Var is an input of a step, an environment variable or a permission.
+	// Its value is either a string, a Quoted string, an int or a bool.
+	Var struct {
+		Name  string
+		Value any
+	}
+	// Quoted is a string written in single quotes, and DoubleQuoted in double
+	// quotes. Other strings are quoted only if they cannot be written as is.
+	Quoted       string
+	DoubleQuoted string
+)
+
+// Marshal returns the YAML document of the workflow.
+func Marshal(w *Workflow) ([]byte, error) {
+	n, err := w.MarshalYAML()
+	if err != nil {
+		return nil, err
+	}
+	return encode(n.(*yaml.Node))
+}
+
+// MarshalYAML implements yaml.Marshaler.
+func (w *Workflow) MarshalYAML() (any, error) {
+	m := &mapping{}
+	m.add("name", str(w.Name))
+	on, err := w.On.node()
+	if err != nil {
+		return nil, err
+	}
+	m.add("on", on)
+	if len(w.Permissions) > 0 {
+		perms, err := vars(w.Permissions)
+		if err != nil {
+			return nil, err
+		}
+		m.add("permissions", perms).HeadComment = comment(w.PermissionsComment)
+	}
+	jobs := &mapping{}
+	for _, j := range w.Jobs {
+		n, err := j.node()
+		if err != nil {
+			return nil, fmt.Errorf("job %q: %w", j.ID, err)
+		}
+		jobs.add(j.ID, n)
+	}
+	m.add("jobs", jobs.node())
+	return m.node(), nil
+}
+
+func (e *Events) node() (*yaml.Node, error) {
+	m := &mapping{}
+	for _, ev := range []struct {
+		name  string
+		event *Event
+	}{{"push", e.Push}, {"pull_request", e.PullRequest}} {
+		if ev.event == nil {
+			continue
+		}
+		n, err := ev.event.node()
+		if err != nil {
+			return nil, err
+		}
+		m.add(ev.name, n)
+	}
+	if e.MergeGroup {
+		m.add("merge_group", null())
+	}
+	if e.Dispatch != nil {
+		m.add("workflow_dispatch", e.Dispatch.node())
+	}
+	return m.node(), nil
+}
+
+func (e *Event) node() (*yaml.Node, error) {
+	m := &mapping{}
+	if len(e.Branches) > 0 {
+		b := strs(e.Branches)
+		if e.Inline {
+			b.Style = yaml.FlowStyle
+		}
+		m.add("branches", b)
+	}
+	if len(e.Paths) > 0 {
+		p, err := list(e.Paths)
+		if err != nil {
+			return nil, err
+		}
+		m.add("paths", p)
+	}
+	if len(m.content) == 0 {
+		return null(), nil
+	}
+	return m.node(), nil
+}
+
+func (d *Dispatch) node() *yaml.Node {
+	if len(d.Inputs) == 0 {
+		return null()
+	}
+	inputs := &mapping{}
+	for _, in := range d.Inputs {
+		m := &mapping{}
+		if in.Description != "" {
+			m.add("description", quoted(in.Description))
+		}
+		m.add("required", &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: fmt.Sprint(in.Required)})
+		if in.Type != "" {
+			m.add("type", str(in.Type))
+		}
+		if in.Default != "" {
+			d := quoted(in.Default)
+			if in.Type == "boolean" || in.Type == "number" {
+				// Let the default be resolved by its type.
+				d = &yaml.Node{Kind: yaml.ScalarNode, Value: in.Default}
+			}
+			m.add("default", d)
+		}
+		if len(in.Options) > 0 {
+			opts := &yaml.Node{Kind: yaml.SequenceNode}
+			for _, o := range in.Options {
+				opts.Content = append(opts.Content, quoted(o))
+			}
+			m.add("options", opts)
+		}
+		inputs.add(in.Name, m.node())
+	}
+	m := &mapping{}
+	m.add("inputs", inputs.node())
+	return m.node()
+}
+
+func (j *Job) node() (*yaml.Node, error) {
+	m := &mapping{}
+	if j.If != "" {
+		m.add("if", str(j.If)).HeadComment = comment(j.Comment)
+	}
+	if len(j.Services) > 0 {
+		services := &mapping{}
+		for _, s := range j.Services {
+			n, err := s.node()
+			if err != nil {
+				return nil, fmt.Errorf("service %q: %w", s.ID, err)
+			}
+			services.add(s.ID, n).HeadComment = comment(s.Comment)
+		}
+		m.add("services", services.node())
+	}
+	switch runsOn := j.RunsOn; len(runsOn) {
+	case 0:
+		m.add("runs-on", str("ubuntu-latest"))
+	case 1:
+		m.add("runs-on", str(runsOn[0]))
+	default:
+		n := strs(runsOn)
+		n.Style = yaml.FlowStyle
+		m.add("runs-on", n)
+	}
+	if j.Container != "" {
+		m.add("container", str(j.Container))
+	}
+	if j.TimeoutMinutes > 0 {
+		m.add("timeout-minutes", &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: fmt.Sprint(j.TimeoutMinutes)})
+	}
+	if len(j.Env) > 0 {
+		env, err := vars(j.Env)
+		if err != nil {
+			return nil, err
+		}
+		m.add("env", env)
+	}
+	steps := &yaml.Node{Kind: yaml.SequenceNode}
+	for i, s := range j.Steps {
+		n, err := s.node()
+		if err != nil {
+			return nil, fmt.Errorf("step %d: %w", i+1, err)
+		}
+		steps.Content = append(steps.Content, n)
+	}
+	m.add("steps", steps)
+	return m.node(), nil
+}
+
+func (s *Service) node() (*yaml.Node, error) {
+	m := &mapping{}
+	m.add("image", str(s.Image))
+	if len(s.Env) > 0 {
+		env, err := vars(s.Env)
+		if err != nil {
+			return nil, err
+		}
+		m.add("env", env)
+	}
+	if len(s.Ports) > 0 {
+		ports, err := list(s.Ports)
+		if err != nil {
+			return nil, err
+		}
+		m.add("ports", ports)
+	}
+	if len(s.Options) > 0 {
+		// Folded scalars are encoded one line per line of their value,
+		// and YAML folds these lines back into a single line.
+		m.add("options", &yaml.Node{Kind: yaml.ScalarNode, Style: yaml.FoldedStyle, Tag: "!!str", Value: strings.Join(s.Options, "\n")})
+	}
+	return m.node(), nil
+}
+
+func (s *Step) node() (*yaml.Node, error) {
+	m := &mapping{}
+	if s.Name != "" {
+		m.add("name", str(s.Name))
+	}
+	if s.Uses != "" {
+		uses := str(s.Uses)
+		uses.LineComment = comment(s.Version)
+		m.add("uses", uses)
+	}
+	if s.ID != "" {
+		m.add("id", str(s.ID))
+	}
+	if s.If != "" {
+		m.add("if", str(s.If))
+	}
+	if len(s.Env) > 0 {
+		env, err := vars(s.Env)
+		if err != nil {
+			return nil, err
+		}
+		m.add("env", env)
+	}
+	if len(s.With) > 0 {
+		with, err := vars(s.With)
+		if err != nil {
+			return nil, err
+		}
+		m.add("with", with)
+	}
+	if len(s.Run) > 0 {
+		m.add("run", &yaml.Node{Kind: yaml.ScalarNode, Style: yaml.FoldedStyle, Tag: "!!str", Value: strings.Join(s.Run, "\n")})
+	}
+	return m.node(), nil
+}
+
+// mapping builds a YAML mapping that keeps the order its keys were added.
+type mapping struct {
+	content []*yaml.Node
+}
+
+// add the key and its value to the mapping, and return the key node.
+func (m *mapping) add(key string, value *yaml.Node) *yaml.Node {
+	k := str(key)
+	m.content = append(m.content, k, value)
+	return k
+}
+
+func (m *mapping) node() *yaml.Node {
+	return &yaml.Node{Kind: yaml.MappingNode, Content: m.content}
+}
+
+// str returns a string scalar. It is quoted only if it cannot be written as is.
+func str(s string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: s}
+}
+
+// strs returns a sequence of string scalars.
+func strs(s []string) *yaml.Node {
+	n := &yaml.Node{Kind: yaml.SequenceNode}
+	for _, v := range s {
+		n.Content = append(n.Content, str(v))
+	}
+	return n
+}
+
+// null returns an empty value, e.g. of an event without configuration.
+func null() *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null"}
+}
+
+// quoted returns a single-quoted string scalar.
+func quoted(s string) *yaml.Node {
+	n := str(s)
+	n.Style = yaml.SingleQuotedStyle
+	return n
+}
+
+// scalar returns the scalar of a string, Quoted, DoubleQuoted, int or bool value.
+func scalar(v any) (*yaml.Node, error) {
+	switch x := v.(type) {
+	case string:
+		return str(x), nil
+	case Quoted:
+		return quoted(string(x)), nil
+	case DoubleQuoted:
+		n := str(string(x))
+		n.Style = yaml.DoubleQuotedStyle
+		return n, nil
+	case int:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: fmt.Sprint(x)}, nil
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: fmt.Sprint(x)}, nil
+	default:
+		return nil, fmt.Errorf("unexpected value type %T", v)
+	}
+}
+
+// list returns a sequence of the scalar values.
+func list(vs []any) (*yaml.Node, error) {
+	n := &yaml.Node{Kind: yaml.SequenceNode}
+	for _, v := range vs {
+		s, err := scalar(v)
+		if err != nil {
+			return nil, err
+		}
+		n.Content = append(n.Content, s)
+	}
+	return n, nil
+}
+
+// vars returns a mapping of the variables.
+func vars(vs []Var) (*yaml.Node, error) {
+	m := &mapping{}
+	for _, v := range vs {
+		n, err := scalar(v.Value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", v.Name, err)
+		}
+		m.add(v.Name, n)
+	}
+	return m.node(), nil
+}
+
+// comment returns s as a YAML comment, or an empty string if s is empty.
+func comment(s string) string {
+	if s == "" {
+		return ""
+	}
+	return "# " + strings.ReplaceAll(s, "\n", "\n# ")
+}
@@ -3,12 +3,19 @@ package main
 import (
 	"context"
 	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
 	"path"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/cli/go-gh"
+	"github.com/cli/go-gh/pkg/api"
+	"github.com/cli/go-gh/pkg/auth"
 	"github.com/cli/go-gh/pkg/repository"
 	"github.com/google/go-github/v49/github"
 	"golang.org/x/crypto/nacl/box"
@@ -21,30 +28,90 @@ type (
 	gitService interface {
 		GetRef(ctx context.Context, owner string, repo string, ref string) (*github.Reference, *github.Response, error)
 		CreateRef(ctx context.Context, owner string, repo string, ref *github.Reference) (*github.Reference, *github.Response, error)
+		DeleteRef(ctx context.Context, owner string, repo string, ref string) (*github.Response, error)
 		GetTree(ctx context.Context, owner string, repo string, sha string, recursive bool) (*github.Tree, *github.Response, error)
+		GetTag(ctx context.Context, owner string, repo string, sha string) (*github.Tag, *github.Response, error)
+		ListMatchingRefs(ctx context.Context, owner, repo string, opts *github.ReferenceListOptions) ([]*github.Reference, *github.Response, error)
 	}
 	// repositoriesService handles communication with the repository related methods of the GitHub API.
 	repositoriesService interface {
 		Get(ctx context.Context, owner, repo string) (*github.Repository, *github.Response, error)
 		GetContents(ctx context.Context, owner, repo, path string, opts *github.RepositoryContentGetOptions) (fileContent *github.RepositoryContent, directoryContent []*github.RepositoryContent, resp *github.Response, err error)
 		CreateFile(ctx context.Context, owner, repo, path string, opts *github.RepositoryContentFileOptions) (*github.RepositoryContentResponse, *github.Response, error)
+		CreateFork(ctx context.Context, owner, repo string, opts *github.RepositoryCreateForkOptions) (*github.Repository, *github.Response, error)
 	}
 	// actionsService handles communication with the actions related methods of the GitHub API.
 	actionsService interface {
 		GetRepoSecret(ctx context.Context, owner, repo, name string) (*github.Secret, *github.Response, error)
 		CreateOrUpdateRepoSecret(ctx context.Context, owner, repo string, eSecret *github.EncryptedSecret) (*github.Response, error)
+		DeleteRepoSecret(ctx context.Context, owner, repo, name string) (*github.Response, error)
 		GetRepoPublicKey(ctx context.Context, owner, repo string) (*github.PublicKey, *github.Response, error)
 	}
 	// pullRequestsService handles communication with the pull request related methods of the GitHub API.
 	pullRequestsService interface {
 		Create(ctx context.Context, owner, repo string, pr *github.NewPullRequest) (*github.PullRequest, *github.Response, error)
+		Get(ctx context.Context, owner, repo string, number int) (*github.PullRequest, *github.Response, error)
+		List(ctx context.Context, owner, repo string, opts *github.PullRequestListOptions) ([]*github.PullRequest, *github.Response, error)
+		RequestReviewers(ctx context.Context, owner, repo string, number int, reviewers github.ReviewersRequest) (*github.PullRequest, *github.Response, error)
+		Edit(ctx context.Context, owner, repo string, number int, pull *github.PullRequest) (*github.PullRequest, *github.Response, error)
+	}
+	// issuesService handles communication with the issue related methods of the GitHub API,
+	// which are used for setting the labels and assignees of pull requests.
+	issuesService interface {
+		AddLabelsToIssue(ctx context.Context, owner, repo string, number int, labels []string) ([]*github.Label, *github.Response, error)
+		AddAssignees(ctx context.Context, owner, repo string, number int, assignees []string) (*github.Issue, *github.Response, error)
+	}
+	// rulesetsService handles communication with the repository rulesets related methods of the GitHub API.
+	rulesetsService interface {
+		GetRulesForBranch(ctx context.Context, owner, repo, branch string) ([]*repositoryRule, *github.Response, error)
+		CreateRuleset(ctx context.Context, owner, repo string, rs *ruleset) (*ruleset, *github.Response, error)
+	}
+	// protectionService handles communication with the branch protection related methods of the GitHub API.
+	protectionService interface {
+		GetBranchProtection(ctx context.Context, owner, repo, branch string) (*github.Protection, *github.Response, error)
+		UpdateRequiredStatusChecks(ctx context.Context, owner, repo, branch string, sreq *github.RequiredStatusChecksRequest) (*github.RequiredStatusChecks, *github.Response, error)
 	}
 	// githubClient is a wrapper around the GitHub API client.
 	githubClient struct {
+		// Host is the hostname of the GitHub instance, e.g. github.com.
+		Host         string
 		Git          gitService
 		Repositories repositoriesService
 		Actions      actionsService
 		PullRequests pullRequestsService
+		Issues       issuesService
+		Rulesets     rulesetsService
+		Protection   protectionService
+	}
+	// repositoryRule is a rule of a repository ruleset.
+	repositoryRule struct {
+		Type       string          `json:"type"`
+		Parameters json.RawMessage `json:"parameters,omitempty"`
+	}
+	// ruleset is a repository ruleset.
+	ruleset struct {
+		ID          int64              `json:"id,omitempty"`
+		Name        string             `json:"name"`
+		Target      string             `json:"target"`
+		Enforcement string             `json:"enforcement"`
+		Conditions  *rulesetConditions `json:"conditions,omitempty"`
+		Rules       []*repositoryRule  `json:"rules,omitempty"`
+	}
+	// rulesetConditions are the refs a ruleset applies to.
+	rulesetConditions struct {
+		RefName struct {
+			Include []string `json:"include"`
+			Exclude []string `json:"exclude"`
+		} `json:"ref_name"`
+	}
+	// requiredStatusChecks are the parameters of the required_status_checks rule.
+	requiredStatusChecks struct {
+		Checks []*github.RequiredStatusCheck `json:"required_status_checks"`
+		Strict bool                          `json:"strict_required_status_checks_policy"`
+	}
+	// rulesets implements the rulesetsService, which is not supported by the go-github client.
+	rulesets struct {
+		client *github.Client
 	}
 	// RepoExplorer defines methods for exploring and retrieving information from a repository.
 	RepoExplorer interface {
@@ -62,6 +129,10 @@ type Repository struct {
 	name          string
 	defaultBranch string
 	client        *githubClient
+	// forkOwner and forkName are set if the branch is pushed
+	// to a fork, as the user cannot push to the repository.
+	forkOwner string
+	forkName  string
 }
 
 var _ RepoExplorer = (*Repository)(nil)
@@ -76,6 +147,77 @@ func NewRepository(client *githubClient, current repository.Repository, defaultB
 	}
 }
 
+// defaultHost is the hostname of GitHub.com.
+const defaultHost = "github.com"
+
+// newGitHubClient creates a client for the GitHub API of the host. If the host
+// is empty, the default host of the gh configuration is used.
+func newGitHubClient(host string) (*githubClient, error) {
+	if host == "" {
+		host, _ = auth.DefaultHost()
+	}
+	c, err := gh.HTTPClient(&api.ClientOptions{Host: host})
+	if err != nil {
+		return nil, err
+	}
+	client := github.NewClient(c)
+	switch {
+	case host == defaultHost:
+	case strings.HasSuffix(host, ".ghe.com"):
+		// GitHub Enterprise Cloud with data residency serves its API on a subdomain.
+		client.BaseURL.Host = "api." + host
+		client.UploadURL.Host = "uploads." + host
+	default:
+		// GitHub Enterprise Server.
+		if client, err = github.NewEnterpriseClient("https://"+host+"/api/v3/", "https://"+host+"/api/uploads/", c); err != nil {
+			return nil, err
+		}
+	}
+	return &githubClient{
+		Host:         host,
+		Git:          client.Git,
+		Repositories: client.Repositories,
+		Actions:      client.Actions,
+		PullRequests: client.PullRequests,
+		Issues:       client.Issues,
+		Rulesets:     &rulesets{client: client},
+		Protection:   client.Repositories,
+	}, nil
+}
+
+// enterpriseServer reports whether the client is connected to a GitHub Enterprise Server.
+func (c *githubClient) enterpriseServer() bool {
+	return c.Host != "" && c.Host != defaultHost && !strings.HasSuffix(c.Host, ".ghe.com")
+}
+
+// GetRulesForBranch returns the active rules that apply to the branch.
+func (s *rulesets) GetRulesForBranch(ctx context.Context, owner, repo, branch string) ([]*repositoryRule, *github.Response, error) {
+	req, err := s.client.NewRequest(http.MethodGet, fmt.Sprintf("repos/%v/%v/rules/branches/%v", owner, repo, branch), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	var rules []*repositoryRule
+	res, err := s.client.Do(ctx, req, &rules)
+	if err != nil {
+		return nil, res, err
+	}
+	return rules, res, nil
+}
+
+// CreateRuleset creates a ruleset for the repository.
+func (s *rulesets) CreateRuleset(ctx context.Context, owner, repo string, rs *ruleset) (*ruleset, *github.Response, error) {
+	req, err := s.client.NewRequest(http.MethodPost, fmt.Sprintf("repos/%v/%v/rulesets", owner, repo), rs)
+	if err != nil {
+		return nil, nil, err
+	}
+	created := new(ruleset)
+	res, err := s.client.Do(ctx, req, created)
+	if err != nil {
+		return nil, res, err
+	}
+	return created, res, nil
+}
+
 // CheckoutNewBranch creates a new branch on top of the default branch.
 func (r *Repository) CheckoutNewBranch(ctx context.Context, branchName string) error {
 	defaultBranch, _, err := r.client.Git.GetRef(ctx, r.owner, r.name, "refs/heads/"+r.defaultBranch)
@@ -88,18 +230,112 @@ func (r *Repository) CheckoutNewBranch(ctx context.Context, branchName string) e
 			SHA: defaultBranch.Object.SHA,
 		},
 	}
-	_, _, err = r.client.Git.CreateRef(ctx, r.owner, r.name, newBranch)
+	// A fork shares the objects of its parent, the branch is created from
+	// the default branch of the repository even if the fork is behind it.
+	owner, name := r.head()
+	_, _, err = r.client.Git.CreateRef(ctx, owner, name, newBranch)
 	return err
 }
 
+// DeleteBranch deletes the branch from the repository the branch is pushed to.
+func (r *Repository) DeleteBranch(ctx context.Context, branch string) error {
+	owner, name := r.head()
+	_, err := r.client.Git.DeleteRef(ctx, owner, name, "refs/heads/"+branch)
+	return err
+}
+
+// DeleteSecret deletes the secret from the repository.
+func (r *Repository) DeleteSecret(ctx context.Context, name string) error {
+	_, err := r.client.Actions.DeleteRepoSecret(ctx, r.owner, r.name, name)
+	return err
+}
+
+// BranchExists reports whether the branch exists in the repository the branch is pushed to.
+func (r *Repository) BranchExists(ctx context.Context, branch string) (bool, error) {
+	owner, name := r.head()
+	_, res, err := r.client.Git.GetRef(ctx, owner, name, "refs/heads/"+branch)
+	switch {
+	case res != nil && res.StatusCode == http.StatusNotFound:
+		return false, nil
+	case err != nil:
+		return false, err
+	default:
+		return true, nil
+	}
+}
+
+// SecretExists reports whether the repository has a secret with the given name.
+func (r *Repository) SecretExists(ctx context.Context, name string) (bool, error) {
+	_, res, err := r.client.Actions.GetRepoSecret(ctx, r.owner, r.name, name)
+	switch {
+	case res != nil && res.StatusCode == http.StatusNotFound:
+		return false, nil
+	case err != nil:
+		return false, err
+	default:
+		return true, nil
+	}
+}
+
+// canPush reports whether the user can push to the repository. If the permissions
+// of the user are unknown, it is assumed the user can push to the repository.
+func canPush(repo *github.Repository) bool {
+	perms := repo.GetPermissions()
+	return len(perms) == 0 || perms["push"]
+}
+
+// Polling of new forks, which are created asynchronously by GitHub.
+var (
+	forkPollAttempts = 15
+	forkPollInterval = 2 * time.Second
+)
+
+// UseFork forks the repository, or reuses the existing fork of the user, and pushes the
+// branch to the fork instead of the repository. It returns the fork in the <owner>/<name> format.
+func (r *Repository) UseFork(ctx context.Context) (string, error) {
+	// The API returns the existing fork of the user if there is one.
+	fork, _, err := r.client.Repositories.CreateFork(ctx, r.owner, r.name, &github.RepositoryCreateForkOptions{DefaultBranchOnly: true})
+	var accepted *github.AcceptedError
+	if err != nil && !errors.As(err, &accepted) {
+		return "", fmt.Errorf("failed to fork %s/%s: %w", r.owner, r.name, err)
+	}
+	r.forkOwner, r.forkName = fork.GetOwner().GetLogin(), fork.GetName()
+	branch := fork.GetDefaultBranch()
+	if branch == "" {
+		branch = r.defaultBranch
+	}
+	for i := 1; ; i++ {
+		_, _, err := r.client.Git.GetRef(ctx, r.forkOwner, r.forkName, "refs/heads/"+branch)
+		if err == nil {
+			return r.forkOwner + "/" + r.forkName, nil
+		}
+		if i == forkPollAttempts {
+			return "", fmt.Errorf("fork %s/%s is not ready: %w", r.forkOwner, r.forkName, err)
+		}
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(forkPollInterval):
+		}
+	}
+}
+
+// head returns the owner and name of the repository the branch is pushed to.
+func (r *Repository) head() (string, string) {
+	if r.forkOwner != "" {
+		return r.forkOwner, r.forkName
+	}
+	return r.owner, r.name
+}
+
 // SetSecret sets Secret for the repository with the given name and value.
-// if the secret already exists, it will not be updated.
-func (r *Repository) SetSecret(ctx context.Context, name, value string) error {
+// if the secret already exists, it will be updated only if overwrite is true.
+func (r *Repository) SetSecret(ctx context.Context, name, value string, overwrite bool) error {
 	_, res, err := r.client.Actions.GetRepoSecret(ctx, r.owner, r.name, name)
 	if err != nil && res.StatusCode != http.StatusNotFound {
 		return err
 	}
-	if res.StatusCode == http.StatusOK {
+	if res.StatusCode == http.StatusOK && !overwrite {
 		return fmt.Errorf("secret %q already exists", name)
 	}
 	key, _, err := r.client.Actions.GetRepoPublicKey(ctx, r.owner, r.name)
@@ -129,9 +365,11 @@ func (r *Repository) SetSecret(ctx context.Context, name, value string) error {
 	return err
 }
 
+// workflowPath is the path of the generated workflow in the repository.
+const workflowPath = ".github/workflows/ci-atlas.yaml"
+
 // AddAtlasYAML create commit with atlas ci yaml file on the branch.
 func (r *Repository) AddAtlasYAML(ctx context.Context, cfg *gen.Config, branchName, commitMsg string, replace bool) error {
-	var actionFilePath = ".github/workflows/ci-atlas.yaml"
 	content, err := gen.Generate(cfg)
 	if err != nil {
 		return err
@@ -141,7 +379,7 @@ func (r *Repository) AddAtlasYAML(ctx context.Context, cfg *gen.Config, branchNa
 		Content: content,
 		Branch:  github.String(branchName),
 	}
-	current, _, _, err := r.client.Repositories.GetContents(ctx, r.owner, r.name, actionFilePath, nil)
+	current, _, _, err := r.client.Repositories.GetContents(ctx, r.owner, r.name, workflowPath, nil)
 	switch e := err.(type) {
 	case nil:
 		if !replace {
@@ -155,23 +393,263 @@ func (r *Repository) AddAtlasYAML(ctx context.Context, cfg *gen.Config, branchNa
 	default:
 		return err
 	}
-	_, _, err = r.client.Repositories.CreateFile(ctx, r.owner, r.name, actionFilePath, newFile)
+	owner, name := r.head()
+	_, _, err = r.client.Repositories.CreateFile(ctx, owner, name, workflowPath, newFile)
 	return err
 }
 
-// CreatePR creates a pull request for the branch and returns the link to the PR.
-func (r *Repository) CreatePR(ctx context.Context, title string, body string, branchName string) (string, error) {
+// UpdateAtlasYAML commits the atlas ci yaml file to an existing branch,
+// and reports whether it was changed. The file is not committed if it is up to date.
+func (r *Repository) UpdateAtlasYAML(ctx context.Context, cfg *gen.Config, branchName, commitMsg string) (bool, error) {
+	content, err := gen.Generate(cfg)
+	if err != nil {
+		return false, err
+	}
+	newFile := &github.RepositoryContentFileOptions{
+		Message: github.String(commitMsg),
+		Content: content,
+		Branch:  github.String(branchName),
+	}
+	owner, name := r.head()
+	current, _, _, err := r.client.Repositories.GetContents(ctx, owner, name, workflowPath, &github.RepositoryContentGetOptions{Ref: branchName})
+	switch e := err.(type) {
+	case nil:
+		old, err := current.GetContent()
+		if err != nil {
+			return false, err
+		}
+		if old == string(content) {
+			return false, nil
+		}
+		newFile.SHA = current.SHA
+	case *github.ErrorResponse:
+		if e.Message != "Not Found" {
+			return false, err
+		}
+	default:
+		return false, err
+	}
+	if _, _, err = r.client.Repositories.CreateFile(ctx, owner, name, workflowPath, newFile); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// PullRequestOptions are the options of the pull request created for the workflow.
+type PullRequestOptions struct {
+	Title string
+	Body  string
+	// Labels are created if they do not exist in the repository.
+	Labels []string
+	// Reviewers are user logins, or teams in the <org>/<team> format.
+	Reviewers []string
+	Assignees []string
+	Draft     bool
+}
+
+// CreatePR creates a pull request for the branch.
+func (r *Repository) CreatePR(ctx context.Context, branchName string, opts *PullRequestOptions) (*github.PullRequest, error) {
 	newPR := &github.NewPullRequest{
-		Title: &title,
+		Title: &opts.Title,
 		Head:  &branchName,
-		Body:  &body,
+		Body:  github.String(markBody(opts.Body)),
 		Base:  &r.defaultBranch,
+		Draft: &opts.Draft,
+	}
+	if r.forkOwner != "" {
+		newPR.Head = github.String(r.forkOwner + ":" + branchName)
+		newPR.MaintainerCanModify = github.Bool(true)
 	}
 	pr, _, err := r.client.PullRequests.Create(ctx, r.owner, r.name, newPR)
+	return pr, err
+}
+
+// EditPR updates the title and body of the pull request.
+func (r *Repository) EditPR(ctx context.Context, number int, opts *PullRequestOptions) (*github.PullRequest, error) {
+	pr, _, err := r.client.PullRequests.Edit(ctx, r.owner, r.name, number, &github.PullRequest{
+		Title: &opts.Title,
+		Body:  github.String(markBody(opts.Body)),
+	})
+	return pr, err
+}
+
+// prMarker is a hidden marker added to the body of the pull requests created by init-action.
+const prMarker = "<!-- gh-atlas:init-action -->"
+
+// markBody adds the marker of init-action to the body of a pull request.
+func markBody(body string) string {
+	return body + "\n\n" + prMarker
+}
+
+// FindPR returns the open pull request created by init-action to the default
+// branch from the repository the branch is pushed to, or nil if there is none.
+func (r *Repository) FindPR(ctx context.Context) (*github.PullRequest, error) {
+	owner, name := r.head()
+	opts := &github.PullRequestListOptions{
+		State:       "open",
+		Base:        r.defaultBranch,
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+	for {
+		prs, res, err := r.client.PullRequests.List(ctx, r.owner, r.name, opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, pr := range prs {
+			// Only branches of the head repository can be updated.
+			if !strings.EqualFold(pr.GetHead().GetRepo().GetFullName(), owner+"/"+name) {
+				continue
+			}
+			if strings.HasPrefix(pr.GetHead().GetRef(), branchPrefix) || strings.Contains(pr.GetBody(), prMarker) {
+				return pr, nil
+			}
+		}
+		if res == nil || res.NextPage == 0 {
+			return nil, nil
+		}
+		opts.Page = res.NextPage
+	}
+}
+
+// UpdatePRMetadata sets the labels, reviewers and assignees of the pull request.
+func (r *Repository) UpdatePRMetadata(ctx context.Context, number int, opts *PullRequestOptions) error {
+	if len(opts.Labels) > 0 {
+		if _, _, err := r.client.Issues.AddLabelsToIssue(ctx, r.owner, r.name, number, opts.Labels); err != nil {
+			return fmt.Errorf("failed to add labels: %w", err)
+		}
+	}
+	if len(opts.Reviewers) > 0 {
+		var req github.ReviewersRequest
+		for _, rv := range opts.Reviewers {
+			if _, team, ok := strings.Cut(rv, "/"); ok {
+				req.TeamReviewers = append(req.TeamReviewers, team)
+			} else {
+				req.Reviewers = append(req.Reviewers, rv)
+			}
+		}
+		if _, _, err := r.client.PullRequests.RequestReviewers(ctx, r.owner, r.name, number, req); err != nil {
+			return fmt.Errorf("failed to request reviewers: %w", err)
+		}
+	}
+	if len(opts.Assignees) > 0 {
+		if _, _, err := r.client.Issues.AddAssignees(ctx, r.owner, r.name, number, opts.Assignees); err != nil {
+			return fmt.Errorf("failed to add assignees: %w", err)
+		}
+	}
+	return nil
+}
+
+// HasMergeQueue reports whether pull requests to the default branch are merged through a merge queue.
+func (r *Repository) HasMergeQueue(ctx context.Context) (bool, error) {
+	rules, _, err := r.client.Rulesets.GetRulesForBranch(ctx, r.owner, r.name, r.defaultBranch)
 	if err != nil {
-		return "", err
+		return false, err
+	}
+	for _, rule := range rules {
+		if rule.Type == "merge_queue" {
+			return true, nil
+		}
 	}
-	return pr.GetHTMLURL(), nil
+	return false, nil
+}
+
+// Where a required check is configured.
+const (
+	requiredByProtection = "branch protection"
+	requiredByRuleset    = "ruleset"
+)
+
+// requiredChecksRuleset is the name of the ruleset created for requiring the Atlas check.
+const requiredChecksRuleset = "Atlas CI"
+
+// errAdminRequired is returned when the user is not allowed to update the branch protection or rulesets.
+var errAdminRequired = errors.New("admin access to the repository is required to update its branch protection or rulesets")
+
+// RequireCheck makes the check required for merging into the default branch. If the branch protection
+// already requires status checks, the check is added to them. Otherwise, a ruleset is created for it.
+// It returns where the check is required, and whether it was added.
+func (r *Repository) RequireCheck(ctx context.Context, check string) (string, bool, error) {
+	rules, res, err := r.client.Rulesets.GetRulesForBranch(ctx, r.owner, r.name, r.defaultBranch)
+	// Rulesets are not available on older GitHub Enterprise versions.
+	if err != nil && (res == nil || res.StatusCode != http.StatusNotFound) {
+		return "", false, adminError(err)
+	}
+	for _, rule := range rules {
+		if rule.Type != "required_status_checks" {
+			continue
+		}
+		var params requiredStatusChecks
+		if err := json.Unmarshal(rule.Parameters, &params); err != nil {
+			return "", false, err
+		}
+		if hasCheck(params.Checks, check) {
+			return requiredByRuleset, false, nil
+		}
+	}
+	p, _, err := r.client.Protection.GetBranchProtection(ctx, r.owner, r.name, r.defaultBranch)
+	switch {
+	case errors.Is(err, github.ErrBranchNotProtected):
+	case err != nil:
+		return "", false, adminError(err)
+	case p.GetRequiredStatusChecks() != nil:
+		checks := p.GetRequiredStatusChecks().Checks
+		if len(checks) == 0 {
+			// Only one of the deprecated contexts and the checks is populated.
+			for _, c := range p.GetRequiredStatusChecks().Contexts {
+				checks = append(checks, &github.RequiredStatusCheck{Context: c})
+			}
+		}
+		if hasCheck(checks, check) {
+			return requiredByProtection, false, nil
+		}
+		_, _, err = r.client.Protection.UpdateRequiredStatusChecks(ctx, r.owner, r.name, r.defaultBranch, &github.RequiredStatusChecksRequest{
+			Checks: append(checks, &github.RequiredStatusCheck{Context: check}),
+		})
+		if err != nil {
+			return "", false, adminError(err)
+		}
+		return requiredByProtection, true, nil
+	}
+	params, err := json.Marshal(&requiredStatusChecks{
+		Checks: []*github.RequiredStatusCheck{{Context: check}},
+	})
+	if err != nil {
+		return "", false, err
+	}
+	rs := &ruleset{
+		Name:        requiredChecksRuleset,
+		Target:      "branch",
+		Enforcement: "active",
+		Conditions:  &rulesetConditions{},
+		Rules:       []*repositoryRule{{Type: "required_status_checks", Parameters: params}},
+	}
+	rs.Conditions.RefName.Include = []string{"~DEFAULT_BRANCH"}
+	rs.Conditions.RefName.Exclude = []string{}
+	if _, _, err = r.client.Rulesets.CreateRuleset(ctx, r.owner, r.name, rs); err != nil {
+		return "", false, adminError(err)
+	}
+	return requiredByRuleset, true, nil
+}
+
+// hasCheck reports whether the check is in the list.
+func hasCheck(checks []*github.RequiredStatusCheck, check string) bool {
+	for _, c := range checks {
+		if c.Context == check {
+			return true
+		}
+	}
+	return false
+}
+
+// adminError returns errAdminRequired if the error is caused by missing permissions.
+// The branch protection API responds with 404 to users without admin access.
+func adminError(err error) error {
+	var e *github.ErrorResponse
+	if errors.As(err, &e) && e.Response != nil &&
+		(e.Response.StatusCode == http.StatusForbidden || e.Response.StatusCode == http.StatusNotFound) {
+		return fmt.Errorf("%w: %s", errAdminRequired, e.Message)
+	}
+	return err
 }
 
 // MigrationDirectories returns a list of paths to directories containing migration files.
@@ -212,3 +690,201 @@ func (r *Repository) ReadContent(ctx context.Context, path string) (string, erro
 	}
 	return fileContents.GetContent()
 }
+
+// ReadBranchContent retrieves the content of a file on a branch of the repository the pull requests are opened from.
+func (r *Repository) ReadBranchContent(ctx context.Context, branch, path string) (string, error) {
+	owner, name := r.head()
+	fileContents, _, _, err := r.client.Repositories.GetContents(ctx, owner, name, path, &github.RepositoryContentGetOptions{Ref: branch})
+	if err != nil {
+		return "", err
+	}
+	return fileContents.GetContent()
+}
+
+// PullRequestHead returns the SHA of the head commit of the pull request with the given number.
+// If number is zero, the open pull request of the given branch is used. Pull requests opened from
+// forks, e.g. by init-action for users without push access, are matched by their branch as well,
+// and the ones opened from the repository itself take precedence.
+func (r *Repository) PullRequestHead(ctx context.Context, number int, branch string) (int, string, error) {
+	if number != 0 {
+		pr, _, err := r.client.PullRequests.Get(ctx, r.owner, r.name, number)
+		if err != nil {
+			return 0, "", err
+		}
+		return pr.GetNumber(), pr.GetHead().GetSHA(), nil
+	}
+	var (
+		found *github.PullRequest
+		opts  = &github.PullRequestListOptions{
+			State:       "open",
+			ListOptions: github.ListOptions{PerPage: 100},
+		}
+	)
+	for {
+		prs, res, err := r.client.PullRequests.List(ctx, r.owner, r.name, opts)
+		if err != nil {
+			return 0, "", err
+		}
+		for _, pr := range prs {
+			if pr.GetHead().GetRef() != branch {
+				continue
+			}
+			if strings.EqualFold(pr.GetHead().GetRepo().GetFullName(), r.owner+"/"+r.name) {
+				return pr.GetNumber(), pr.GetHead().GetSHA(), nil
+			}
+			if found == nil {
+				found = pr
+			}
+		}
+		if res == nil || res.NextPage == 0 {
+			break
+		}
+		opts.Page = res.NextPage
+	}
+	if found == nil {
+		return 0, "", fmt.Errorf("no open pull request found for branch %q", branch)
+	}
+	return found.GetNumber(), found.GetHead().GetSHA(), nil
+}
+
+// PinActions resolves the tags of the actions used by the workflow
+// to their commits, and sets them as the pins of the config.
+func (r *Repository) PinActions(ctx context.Context, cfg *gen.Config) error {
+	actions, err := gen.Actions(cfg)
+	if err != nil {
+		return err
+	}
+	cfg.Pins = make(map[string]gen.Pin, len(actions))
+	for _, a := range actions {
+		repo, _, tag := gen.SplitAction(a)
+		owner, name, _ := strings.Cut(repo, "/")
+		pin, err := r.resolveTag(ctx, owner, name, tag)
+		if err != nil {
+			return fmt.Errorf("failed to pin action %s: %w", a, err)
+		}
+		cfg.Pins[a] = *pin
+	}
+	return nil
+}
+
+// MissingActions returns the repositories of the actions used by the workflow that
+// do not exist on the GitHub instance. On GitHub Enterprise Server, such actions can be
+// used only if GitHub Connect is enabled, or if they are mirrored to the instance.
+func (r *Repository) MissingActions(ctx context.Context, cfg *gen.Config) ([]string, error) {
+	actions, err := gen.Actions(cfg)
+	if err != nil {
+		return nil, err
+	}
+	var missing []string
+	for _, a := range actions {
+		repo, _, _ := gen.SplitAction(a)
+		if slices.Contains(missing, repo) {
+			continue
+		}
+		owner, name, _ := strings.Cut(repo, "/")
+		_, res, err := r.client.Repositories.Get(ctx, owner, name)
+		switch {
+		case res != nil && res.StatusCode == http.StatusNotFound:
+			missing = append(missing, repo)
+		case err != nil:
+			return nil, err
+		}
+	}
+	return missing, nil
+}
+
+// resolveTag returns the commit the tag points to, along with
+// the most specific version tag pointing to the same commit.
+func (r *Repository) resolveTag(ctx context.Context, owner, repo, tag string) (*gen.Pin, error) {
+	ref, _, err := r.client.Git.GetRef(ctx, owner, repo, "tags/"+tag)
+	if err != nil {
+		return nil, err
+	}
+	sha, err := r.refCommit(ctx, owner, repo, ref)
+	if err != nil {
+		return nil, err
+	}
+	pin := &gen.Pin{SHA: sha, Version: tag}
+	// Major version tags, such as v3, are moved on every release.
+	// Look for the release tag of the same commit, such as v3.6.0.
+	refs, _, err := r.client.Git.ListMatchingRefs(ctx, owner, repo, &github.ReferenceListOptions{
+		Ref:         "tags/" + tag + ".",
+		ListOptions: github.ListOptions{PerPage: 100},
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, ref := range refs {
+		v := strings.TrimPrefix(ref.GetRef(), "refs/tags/")
+		if compareVersions(v, pin.Version) <= 0 {
+			continue
+		}
+		c, err := r.refCommit(ctx, owner, repo, ref)
+		if err != nil {
+			return nil, err
+		}
+		if c == sha {
+			pin.Version = v
+		}
+	}
+	return pin, nil
+}
+
+// refCommit returns the SHA of the commit the reference points to,
+// dereferencing annotated tags.
+func (r *Repository) refCommit(ctx context.Context, owner, repo string, ref *github.Reference) (string, error) {
+	if ref.GetObject().GetType() != "tag" {
+		return ref.GetObject().GetSHA(), nil
+	}
+	t, _, err := r.client.Git.GetTag(ctx, owner, repo, ref.GetObject().GetSHA())
+	if err != nil {
+		return "", err
+	}
+	return t.GetObject().GetSHA(), nil
+}
+
+// compareVersions compares two version tags, such as v3 and v3.6.0, by their
+// numeric parts. Tags that are not plain versions, such as v3.6.0-beta, are
+// considered lower than any plain version.
+func compareVersions(a, b string) int {
+	va, oka := versionParts(a)
+	vb, okb := versionParts(b)
+	switch {
+	case !oka && !okb:
+		return 0
+	case !oka:
+		return -1
+	case !okb:
+		return 1
+	}
+	for i := 0; i < len(va) || i < len(vb); i++ {
+		var x, y int
+		if i < len(va) {
+			x = va[i]
+		}
+		if i < len(vb) {
+			y = vb[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return len(va) - len(vb)
+}
+
+// versionParts returns the numeric parts of a version tag.
+func versionParts(v string) ([]int, bool) {
+	fields := strings.Split(strings.TrimPrefix(v, "v"), ".")
+	parts := make([]int, len(fields))
+	for i, f := range fields {
+		n, err := strconv.Atoi(f)
+		if err != nil {
+			return nil, false
+		}
+		parts[i] = n
+	}
+	return parts, true
+}
@@ -2,12 +2,22 @@ package main
 
 import (
 	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
 	"testing"
 
 	"github.com/cli/go-gh/pkg/repository"
 	"github.com/google/go-github/v49/github"
 	"github.com/google/go-replayers/httpreplay"
 	"github.com/stretchr/testify/require"
+
+	"ariga.io/gh-atlas/gen"
 )
 
 func TestGitHubMigrationDirectories(t *testing.T) {
@@ -28,3 +38,396 @@ func TestGitHubMigrationDirectories(t *testing.T) {
 	require.NoError(t, err)
 	require.Len(t, dirs, 2)
 }
+
+// mockTagsService is a mock of the git service serving the tags of the actions.
+type mockTagsService struct {
+	mockService
+	// refs maps the repositories to their tag references.
+	refs map[string][]*github.Reference
+	// tags maps the annotated tag objects to their commits.
+	tags map[string]string
+}
+
+func (m *mockTagsService) GetRef(_ context.Context, owner, repo, ref string) (*github.Reference, *github.Response, error) {
+	for _, r := range m.refs[owner+"/"+repo] {
+		if r.GetRef() == "refs/"+ref {
+			return r, nil, nil
+		}
+	}
+	return nil, nil, fmt.Errorf("ref %s not found", ref)
+}
+
+func (m *mockTagsService) GetTag(_ context.Context, _, _, sha string) (*github.Tag, *github.Response, error) {
+	return &github.Tag{Object: &github.GitObject{Type: github.String("commit"), SHA: github.String(m.tags[sha])}}, nil, nil
+}
+
+func (m *mockTagsService) ListMatchingRefs(_ context.Context, owner, repo string, opts *github.ReferenceListOptions) ([]*github.Reference, *github.Response, error) {
+	var refs []*github.Reference
+	for _, r := range m.refs[owner+"/"+repo] {
+		if strings.HasPrefix(r.GetRef(), "refs/"+opts.Ref) {
+			refs = append(refs, r)
+		}
+	}
+	return refs, nil, nil
+}
+
+func tagRef(name, typ, sha string) *github.Reference {
+	return &github.Reference{
+		Ref:    github.String("refs/tags/" + name),
+		Object: &github.GitObject{Type: github.String(typ), SHA: github.String(sha)},
+	}
+}
+
+func TestPinActions(t *testing.T) {
+	git := &mockTagsService{
+		refs: map[string][]*github.Reference{
+			"actions/checkout": {
+				tagRef("v3", "commit", "c1"),
+				tagRef("v3.5.3", "commit", "c0"),
+				tagRef("v3.6.0", "commit", "c1"),
+				tagRef("v3.6.0-beta", "commit", "c1"),
+			},
+			"ariga/setup-atlas": {
+				tagRef("v0", "tag", "t1"),
+				tagRef("v0.2.0", "tag", "t2"),
+				tagRef("v0.10.0", "tag", "t3"),
+			},
+			"ariga/atlas-action": {
+				tagRef("v1", "commit", "a1"),
+			},
+		},
+		tags: map[string]string{"t1": "s2", "t2": "s1", "t3": "s2"},
+	}
+	currRepo, err := repository.Parse("ariga/gh-atlas")
+	require.NoError(t, err)
+	repo := NewRepository(&githubClient{Git: git}, currRepo, "master")
+	cfg := &gen.Config{
+		Flow:          "versioned",
+		Path:          "migrations",
+		DirName:       "name",
+		DefaultBranch: "master",
+		SecretName:    "ATLAS_CLOUD_TOKEN",
+		Driver:        "MYSQL",
+	}
+	require.NoError(t, repo.PinActions(context.Background(), cfg))
+	require.Equal(t, map[string]gen.Pin{
+		"actions/checkout@v3":   {SHA: "c1", Version: "v3.6.0"},
+		"ariga/setup-atlas@v0":  {SHA: "s2", Version: "v0.10.0"},
+		"ariga/atlas-action@v1": {SHA: "a1", Version: "v1"},
+	}, cfg.Pins)
+	content, err := gen.Generate(cfg)
+	require.NoError(t, err)
+	require.Contains(t, string(content), "uses: actions/checkout@c1 # v3.6.0")
+	require.Contains(t, string(content), "uses: ariga/atlas-action/migrate/lint@a1 # v1")
+
+	git.refs["ariga/atlas-action"] = nil
+	err = repo.PinActions(context.Background(), cfg)
+	require.EqualError(t, err, "failed to pin action ariga/atlas-action@v1: ref tags/v1 not found")
+}
+
+func TestCompareVersions(t *testing.T) {
+	for _, tt := range []struct {
+		a, b string
+		cmp  int
+	}{
+		{"v3", "v3", 0},
+		{"v3.6.0", "v3", 1},
+		{"v3.10.0", "v3.9.1", 1},
+		{"v3.6.0-beta", "v3", -1},
+		{"v0.1.0", "v0.2.0", -1},
+	} {
+		require.Equal(t, tt.cmp, sign(compareVersions(tt.a, tt.b)), "%s <=> %s", tt.a, tt.b)
+	}
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}
+
+func TestHasMergeQueue(t *testing.T) {
+	var rules string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/repos/ariga/gh-atlas/rules/branches/master", r.URL.Path)
+		if rules == "" {
+			w.WriteHeader(http.StatusNotFound)
+			rules = `{"message":"Not Found"}`
+		}
+		_, _ = w.Write([]byte(rules))
+	}))
+	defer srv.Close()
+	client := github.NewClient(nil)
+	u, err := url.Parse(srv.URL + "/")
+	require.NoError(t, err)
+	client.BaseURL = u
+	currRepo, err := repository.Parse("ariga/gh-atlas")
+	require.NoError(t, err)
+	repo := NewRepository(&githubClient{Rulesets: &rulesets{client: client}}, currRepo, "master")
+
+	_, err = repo.HasMergeQueue(context.Background())
+	require.Error(t, err)
+
+	rules = `[{"type":"pull_request","parameters":{"required_approving_review_count":1}}]`
+	ok, err := repo.HasMergeQueue(context.Background())
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	rules = `[{"type":"deletion"},{"type":"merge_queue","parameters":{"merge_method":"SQUASH"}}]`
+	ok, err = repo.HasMergeQueue(context.Background())
+	require.NoError(t, err)
+	require.True(t, ok)
+}
+
+// fakeGitHub is a fake GitHub API serving the rulesets and branch protection of ariga/gh-atlas.
+type fakeGitHub struct {
+	status     int      // status of all responses, if set
+	rules      string   // rules of the default branch
+	protection string   // protection of the default branch, if protected
+	workflow   string   // content of the Atlas workflow, if exists
+	missing    []string // repositories that do not exist
+	fork       string   // fork of the repository, if created
+	// forkPending is the number of ref requests to the fork that fail before it is ready.
+	forkPending int
+	// requests made to the server, with their bodies.
+	requests []string
+}
+
+func (f *fakeGitHub) client(t *testing.T) *githubClient {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		f.requests = append(f.requests, strings.TrimSpace(r.Method+" "+r.URL.Path+" "+string(b)))
+		switch {
+		case f.status != 0:
+			w.WriteHeader(f.status)
+			fmt.Fprint(w, `{"message":"Must have admin rights to Repository."}`)
+		case slices.Contains(f.missing, strings.TrimPrefix(r.URL.Path, "/repos/")):
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message":"Not Found"}`)
+		case r.URL.Path == "/repos/ariga/gh-atlas":
+			fmt.Fprint(w, `{"default_branch":"master"}`)
+		case r.URL.Path == "/repos/ariga/gh-atlas/forks":
+			w.WriteHeader(http.StatusAccepted)
+			fmt.Fprint(w, f.fork)
+		case strings.Contains(r.URL.Path, "/git/ref/") && !strings.HasPrefix(r.URL.Path, "/repos/ariga/") && f.forkPending > 0:
+			f.forkPending--
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message":"Git Repository is empty."}`)
+		case strings.Contains(r.URL.Path, "/git/ref/"):
+			fmt.Fprint(w, `{"ref":"refs/heads/master","object":{"sha":"4b825dc"}}`)
+		case r.URL.Path == "/repos/ariga/gh-atlas/contents/"+workflowPath && r.Method != http.MethodGet:
+			fmt.Fprint(w, `{}`)
+		case r.URL.Path == "/repos/ariga/gh-atlas/contents/"+workflowPath && f.workflow == "":
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message":"Not Found"}`)
+		case r.URL.Path == "/repos/ariga/gh-atlas/contents/"+workflowPath:
+			fmt.Fprintf(w, `{"type":"file","encoding":"base64","content":%q}`, base64.StdEncoding.EncodeToString([]byte(f.workflow)))
+		case r.URL.Path == "/repos/ariga/gh-atlas/rules/branches/master":
+			fmt.Fprint(w, f.rules)
+		case r.URL.Path == "/repos/ariga/gh-atlas/branches/master/protection" && f.protection == "":
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message":"Branch not protected"}`)
+		case r.URL.Path == "/repos/ariga/gh-atlas/branches/master/protection":
+			fmt.Fprint(w, f.protection)
+		default:
+			fmt.Fprint(w, `{}`)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	client := github.NewClient(nil)
+	u, err := url.Parse(srv.URL + "/")
+	require.NoError(t, err)
+	client.BaseURL = u
+	return &githubClient{
+		Git:          client.Git,
+		Repositories: client.Repositories,
+		Actions:      client.Actions,
+		PullRequests: client.PullRequests,
+		Rulesets:     &rulesets{client: client},
+		Protection:   client.Repositories,
+	}
+}
+
+func TestUseFork(t *testing.T) {
+	currRepo, err := repository.Parse("ariga/gh-atlas")
+	require.NoError(t, err)
+	interval := forkPollInterval
+	forkPollInterval = 0
+	t.Cleanup(func() { forkPollInterval = interval })
+
+	gh := &fakeGitHub{fork: `{"name":"gh-atlas","owner":{"login":"octocat"},"default_branch":"master"}`, forkPending: 2}
+	repo := NewRepository(gh.client(t), currRepo, "master")
+	fork, err := repo.UseFork(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "octocat/gh-atlas", fork)
+	require.NoError(t, repo.CheckoutNewBranch(context.Background(), "atlas-ci-1"))
+	require.NoError(t, repo.AddAtlasYAML(context.Background(), &gen.Config{Flow: "versioned", Path: "migrations", DefaultBranch: "master", Driver: "MYSQL", Offline: true}, "atlas-ci-1", commitMsg, false))
+	_, err = repo.CreatePR(context.Background(), "atlas-ci-1", &PullRequestOptions{Title: commitMsg})
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		`POST /repos/ariga/gh-atlas/forks {"default_branch_only":true}`,
+		"GET /repos/octocat/gh-atlas/git/ref/heads/master",
+		"GET /repos/octocat/gh-atlas/git/ref/heads/master",
+		"GET /repos/octocat/gh-atlas/git/ref/heads/master",
+		// The branch is created on the fork from the default branch of the repository.
+		"GET /repos/ariga/gh-atlas/git/ref/heads/master",
+		`POST /repos/octocat/gh-atlas/git/refs {"ref":"refs/heads/atlas-ci-1","sha":"4b825dc"}`,
+		"GET /repos/ariga/gh-atlas/contents/" + workflowPath,
+		`POST /repos/ariga/gh-atlas/pulls {"title":".github/workflows: add atlas ci workflow","head":"octocat:atlas-ci-1","base":"master","body":"\n\n<!-- gh-atlas:init-action -->","maintainer_can_modify":true,"draft":false}`,
+	}, slices.Delete(slices.Clone(gh.requests), 7, 8))
+	require.True(t, strings.HasPrefix(gh.requests[7], "PUT /repos/octocat/gh-atlas/contents/"+workflowPath))
+
+	gh = &fakeGitHub{fork: `{"name":"gh-atlas","owner":{"login":"octocat"}}`, forkPending: forkPollAttempts}
+	_, err = NewRepository(gh.client(t), currRepo, "master").UseFork(context.Background())
+	require.ErrorContains(t, err, "fork octocat/gh-atlas is not ready")
+
+	gh = &fakeGitHub{status: http.StatusForbidden}
+	_, err = NewRepository(gh.client(t), currRepo, "master").UseFork(context.Background())
+	require.ErrorContains(t, err, "failed to fork ariga/gh-atlas")
+}
+
+func TestSetSecret(t *testing.T) {
+	currRepo, err := repository.Parse("ariga/gh-atlas")
+	require.NoError(t, err)
+	gh := &fakeGitHub{}
+	repo := NewRepository(gh.client(t), currRepo, "master")
+	err = repo.SetSecret(context.Background(), "ATLAS_CLOUD_TOKEN_1", "token", false)
+	require.EqualError(t, err, `secret "ATLAS_CLOUD_TOKEN_1" already exists`)
+	require.NoError(t, repo.SetSecret(context.Background(), "ATLAS_CLOUD_TOKEN_1", "token", true))
+	require.True(t, strings.HasPrefix(gh.requests[len(gh.requests)-1], "PUT /repos/ariga/gh-atlas/actions/secrets/ATLAS_CLOUD_TOKEN_1"))
+}
+
+func TestUpdateAtlasYAML(t *testing.T) {
+	currRepo, err := repository.Parse("ariga/gh-atlas")
+	require.NoError(t, err)
+	cfg := &gen.Config{Flow: "versioned", Path: "migrations", DefaultBranch: "master", Driver: "MYSQL", Offline: true}
+	content, err := gen.Generate(cfg)
+	require.NoError(t, err)
+
+	gh := &fakeGitHub{workflow: string(content)}
+	changed, err := NewRepository(gh.client(t), currRepo, "master").UpdateAtlasYAML(context.Background(), cfg, "atlas-ci-1", commitMsg)
+	require.NoError(t, err)
+	require.False(t, changed)
+	require.Equal(t, []string{"GET /repos/ariga/gh-atlas/contents/" + workflowPath}, gh.requests)
+
+	gh = &fakeGitHub{workflow: "name: Atlas"}
+	changed, err = NewRepository(gh.client(t), currRepo, "master").UpdateAtlasYAML(context.Background(), cfg, "atlas-ci-1", commitMsg)
+	require.NoError(t, err)
+	require.True(t, changed)
+	require.True(t, strings.HasPrefix(gh.requests[1], "PUT /repos/ariga/gh-atlas/contents/"+workflowPath))
+}
+
+func TestCanPush(t *testing.T) {
+	require.True(t, canPush(&github.Repository{}))
+	require.True(t, canPush(&github.Repository{Permissions: map[string]bool{"pull": true, "push": true}}))
+	require.False(t, canPush(&github.Repository{Permissions: map[string]bool{"pull": true, "push": false}}))
+}
+
+func TestRequireCheck(t *testing.T) {
+	currRepo, err := repository.Parse("ariga/gh-atlas")
+	require.NoError(t, err)
+	for _, tt := range []struct {
+		name       string
+		gh         *fakeGitHub
+		wantBy     string
+		wantAdded  bool
+		wantErr    error
+		wantLastRq string
+	}{
+		{
+			name:   "required by ruleset",
+			gh:     &fakeGitHub{rules: `[{"type":"required_status_checks","parameters":{"required_status_checks":[{"context":"atlas"}]}}]`},
+			wantBy: requiredByRuleset,
+		},
+		{
+			name:   "required by protection contexts",
+			gh:     &fakeGitHub{rules: `[]`, protection: `{"required_status_checks":{"contexts":["build","atlas"]}}`},
+			wantBy: requiredByProtection,
+		},
+		{
+			name:       "add to protection",
+			gh:         &fakeGitHub{rules: `[]`, protection: `{"required_status_checks":{"checks":[{"context":"build","app_id":1}]}}`},
+			wantBy:     requiredByProtection,
+			wantAdded:  true,
+			wantLastRq: `PATCH /repos/ariga/gh-atlas/branches/master/protection/required_status_checks {"checks":[{"context":"build","app_id":1},{"context":"atlas"}]}`,
+		},
+		{
+			name:       "create ruleset",
+			gh:         &fakeGitHub{rules: `[{"type":"deletion"}]`},
+			wantBy:     requiredByRuleset,
+			wantAdded:  true,
+			wantLastRq: `POST /repos/ariga/gh-atlas/rulesets {"name":"Atlas CI","target":"branch","enforcement":"active","conditions":{"ref_name":{"include":["~DEFAULT_BRANCH"],"exclude":[]}},"rules":[{"type":"required_status_checks","parameters":{"required_status_checks":[{"context":"atlas"}],"strict_required_status_checks_policy":false}}]}`,
+		},
+		{
+			name:       "protection without status checks",
+			gh:         &fakeGitHub{rules: `[]`, protection: `{"required_pull_request_reviews":{}}`},
+			wantBy:     requiredByRuleset,
+			wantAdded:  true,
+			wantLastRq: `POST /repos/ariga/gh-atlas/rulesets {"name":"Atlas CI","target":"branch","enforcement":"active","conditions":{"ref_name":{"include":["~DEFAULT_BRANCH"],"exclude":[]}},"rules":[{"type":"required_status_checks","parameters":{"required_status_checks":[{"context":"atlas"}],"strict_required_status_checks_policy":false}}]}`,
+		},
+		{
+			name:    "forbidden",
+			gh:      &fakeGitHub{status: http.StatusForbidden},
+			wantErr: errAdminRequired,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := NewRepository(tt.gh.client(t), currRepo, "master")
+			by, added, err := repo.RequireCheck(context.Background(), "atlas")
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.wantBy, by)
+			require.Equal(t, tt.wantAdded, added)
+			if tt.wantLastRq != "" {
+				require.Equal(t, tt.wantLastRq, tt.gh.requests[len(tt.gh.requests)-1])
+			}
+		})
+	}
+}
+
+func TestNewGitHubClient(t *testing.T) {
+	t.Setenv("GH_TOKEN", "token")
+	t.Setenv("GH_ENTERPRISE_TOKEN", "token")
+	for host, want := range map[string]string{
+		"github.com":         "https://api.github.com/",
+		"octocorp.ghe.com":   "https://api.octocorp.ghe.com/",
+		"github.example.com": "https://github.example.com/api/v3/",
+	} {
+		c, err := newGitHubClient(host)
+		require.NoError(t, err)
+		require.Equal(t, host, c.Host)
+		require.Equal(t, want, c.Rulesets.(*rulesets).client.BaseURL.String())
+		require.Equal(t, host == "github.example.com", c.enterpriseServer())
+	}
+}
+
+func TestMissingActions(t *testing.T) {
+	currRepo, err := repository.Parse("ariga/gh-atlas")
+	require.NoError(t, err)
+	gh := &fakeGitHub{missing: []string{"ariga/setup-atlas", "ariga/atlas-action"}}
+	repo := NewRepository(gh.client(t), currRepo, "master")
+	cfg := &gen.Config{
+		Flow:          "versioned",
+		Path:          "migrations",
+		DirName:       "name",
+		DefaultBranch: "master",
+		SecretName:    "ATLAS_CLOUD_TOKEN",
+		Driver:        "MYSQL",
+	}
+	missing, err := repo.MissingActions(context.Background(), cfg)
+	require.NoError(t, err)
+	require.Equal(t, []string{"ariga/atlas-action", "ariga/setup-atlas"}, missing)
+
+	cfg.Mirrors = map[string]string{"ariga/atlas-action": "mirrors/atlas-action"}
+	missing, err = repo.MissingActions(context.Background(), cfg)
+	require.NoError(t, err)
+	require.Equal(t, []string{"ariga/setup-atlas"}, missing)
+}
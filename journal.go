@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/1lann/promptui"
+)
+
+type (
+	// journal records the mutations made by a command, to undo them if the command fails.
+	journal struct {
+		steps []step
+	}
+	// step is a mutation recorded in the journal.
+	step struct {
+		// name describes the mutation, e.g., `branch "atlas-ci-1A2B3C"`.
+		name string
+		// undo compensates the mutation.
+		undo func(context.Context) error
+	}
+)
+
+// record adds a mutation and its compensation to the journal.
+func (j *journal) record(name string, undo func(context.Context) error) {
+	j.steps = append(j.steps, step{name: name, undo: undo})
+}
+
+// commit discards the recorded mutations, once they should not be undone anymore.
+func (j *journal) commit() {
+	j.steps = nil
+}
+
+// rollback undoes the recorded mutations in reverse order. It continues on failures, and
+// returns the errors of the mutations that were not undone, so they can be cleaned up manually.
+func (j *journal) rollback(ctx context.Context, w io.Writer) error {
+	var errs []error
+	for i := len(j.steps) - 1; i >= 0; i-- {
+		s := j.steps[i]
+		if err := s.undo(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("failed to delete %s: %w", s.name, err))
+			continue
+		}
+		fmt.Fprintf(w, "%s %s %s\n",
+			promptui.IconGood,
+			promptui.Styler(promptui.FGFaint)("Rolled back, deleted"),
+			s.name,
+		)
+	}
+	j.steps = nil
+	return errors.Join(errs...)
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/cli/go-gh/pkg/repository"
+	"github.com/google/go-github/v49/github"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJournal(t *testing.T) {
+	var (
+		j    journal
+		undo []string
+		out  bytes.Buffer
+	)
+	for _, name := range []string{"a", "b", "c"} {
+		name := name
+		j.record(name, func(context.Context) error {
+			undo = append(undo, name)
+			if name == "b" {
+				return errors.New("not found")
+			}
+			return nil
+		})
+	}
+	err := j.rollback(context.Background(), &out)
+	require.EqualError(t, err, "failed to delete b: not found")
+	require.Equal(t, []string{"c", "b", "a"}, undo)
+	require.Contains(t, out.String(), "Rolled back, deleted\x1b[0m c")
+	require.NotContains(t, out.String(), " b\n")
+	require.NoError(t, j.rollback(context.Background(), &out), "steps are undone once")
+
+	j.record("d", func(context.Context) error {
+		t.Fatal("committed steps should not be undone")
+		return nil
+	})
+	j.commit()
+	require.NoError(t, j.rollback(context.Background(), &out))
+}
+
+func TestRunInitActionCmd_Rollback(t *testing.T) {
+	current, err := repository.Parse("owner/repo")
+	require.NoError(t, err)
+	var (
+		branch = "refs/heads/atlas-ci-0"
+		secret = "secret:ATLAS_CLOUD_TOKEN_0"
+	)
+	for _, tt := range []struct {
+		name        string
+		failOn      string // failing method of the GitHub mock
+		createErr   error  // error of the PR creation
+		cmd         InitActionCmd
+		wantErr     string
+		wantDeleted []string
+	}{
+		{
+			name:    "set secret",
+			failOn:  "CreateOrUpdateRepoSecret",
+			wantErr: "CreateOrUpdateRepoSecret failed",
+		},
+		{
+			name:        "create branch",
+			failOn:      "CreateRef",
+			wantErr:     "CreateRef failed",
+			wantDeleted: []string{secret},
+		},
+		{
+			// The templates are executed before the secret and the branch are created.
+			name:    "execute PR templates",
+			cmd:     InitActionCmd{PRTitle: "{{ .Unknown }}"},
+			wantErr: "failed to execute the pull request title",
+		},
+		{
+			name:        "commit workflow",
+			failOn:      "CreateFile",
+			wantErr:     "CreateFile failed",
+			wantDeleted: []string{branch, secret},
+		},
+		{
+			name:        "create PR",
+			createErr:   errors.New("create PR failed"),
+			wantErr:     "create PR failed",
+			wantDeleted: []string{branch, secret},
+		},
+		{
+			name:      "keep on failure",
+			createErr: errors.New("create PR failed"),
+			cmd:       InitActionCmd{KeepOnFailure: true},
+			wantErr:   "create PR failed",
+		},
+		{
+			name:        "rollback fails",
+			failOn:      "DeleteRef",
+			createErr:   errors.New("create PR failed"),
+			wantErr:     "create PR failed\nfailed to delete branch \"atlas-ci-0\": DeleteRef failed",
+			wantDeleted: []string{secret},
+		},
+		{
+			name:    "after PR creation",
+			cmd:     InitActionCmd{outputFlags: outputFlags{JQ: ".["}},
+			wantErr: "unexpected EOF",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			gh := &mockService{getContentError: &github.ErrorResponse{Message: "Not Found"}, failOn: tt.failOn}
+			client := createGHClient(gh, gh)
+			client.Actions = gh
+			client.PullRequests = &mockPullRequestsService{createErr: tt.createErr}
+			cmd := &tt.cmd
+			cmd.Token, cmd.CloudURL, cmd.DirPath, cmd.SchemaScope = "token", fakeCloudURL(t), "migrations", true
+			cmd.stdin, cmd.stdout, cmd.stderr = &stdinBuffer{strings.NewReader("")}, &out, &out
+			cmd.naming = seqNaming{}
+			err := cmd.Run(context.Background(), client, current)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tt.wantErr)
+			require.Equal(t, tt.wantDeleted, gh.deleted)
+		})
+	}
+}
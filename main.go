@@ -5,8 +5,8 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"log"
-	"math/rand"
+	"os"
+	"strings"
 
 	"github.com/1lann/promptui"
 	"github.com/alecthomas/kong"
@@ -19,81 +19,152 @@ import (
 )
 
 func main() {
-	c, err := gh.HTTPClient(nil)
-	if err != nil {
-		log.Fatal(err)
-	}
-	client := github.NewClient(c)
-	ghClient := &githubClient{
-		Git:          client.Git,
-		Repositories: client.Repositories,
-		Actions:      client.Actions,
-		PullRequests: client.PullRequests,
-	}
-	currRepo, err := gh.CurrentRepository()
-	if err != nil {
-		log.Fatal(err)
-	}
 	opts := []kong.Option{
 		kong.BindTo(context.Background(), (*context.Context)(nil)),
-		kong.BindTo(currRepo, (*repository.Repository)(nil)),
+		// The current repository and the GitHub client are resolved only by commands that require them.
+		kong.BindToProvider(gh.CurrentRepository),
+		kong.BindToProvider(func() (*githubClient, error) {
+			return newGitHubClient(cli.Hostname)
+		}),
 		kong.UsageOnError(),
+		kong.Vars{"cloud_url": cloudapi.DefaultURL},
 	}
+	cli.InitAction.tokenSources = defaultTokenSources
+	cli.Cloud.tokenSources = defaultTokenSources
+	cli.Report.tokenSources = defaultTokenSources
+	cli.Plan.tokenSources = defaultTokenSources
 	ctx := kong.Parse(&cli, opts...)
-	err = ctx.Run(context.Background(), ghClient, currRepo)
+	err := ctx.Run(context.Background())
 	ctx.FatalIfErrorf(err)
 }
 
 // cli is the root command.
 var cli struct {
-	InitAction InitActionCmd `cmd:"" help:"Initialize a new Atlas CI Action configuration."`
+	Hostname     string          `env:"GH_HOST" help:"Hostname of the GitHub instance, defaults to the host of the gh configuration."`
+	InitAction   InitActionCmd   `cmd:"" help:"Initialize a new Atlas CI Action configuration."`
+	Cloud        CloudCmd        `cmd:"" help:"Browse Atlas Cloud from the terminal."`
+	Report       ReportCmd       `cmd:"" help:"Show the Atlas Cloud CI reports of a pull request."`
+	Plan         PlanCmd         `cmd:"" help:"Review declarative schema plans."`
+	RequireCheck RequireCheckCmd `cmd:"" help:"Require the Atlas check for merging into the default branch."`
 }
 
 // InitActionCmd is the command for initializing a new Atlas CI workflow.
 type InitActionCmd struct {
-	From             string        `optional:"" help:"URL of the current schema state."`
-	To               string        `optional:"" help:"URL of the desired schema state."`
-	DirPath          string        `arg:"" optional:"" type:"-path" help:"Path inside repository containing the migration files."`
-	Token            string        `short:"t" help:"Atlas authentication token."`
-	Repo             string        `short:"R" help:"GitHub repository <owner>/<name>, defaults to the current repository."`
-	ConfigPath       string        `optional:"" help:"Path to atlas.hcl configuration file."`
-	ConfigEnv        string        `optional:"" help:"The environment to use from the Atlas configuration file."`
-	SchemaScope      bool          `optional:"" help:"Limit the scope of the work done by Atlas (inspection, diffing, etc.) to one schema."`
-	DirName          string        `optional:"" help:"Name of target migration directory in Atlas Cloud."`
-	Replace          bool          `optional:"" help:"Replace existing Atlas CI workflow."`
-	SetupSchemaApply *bool         `name:"schema-apply" help:"Whether to setup the 'schema apply' action."`
-	driver           string        `hidden:"" help:"Driver of the migration directory (mysql,postgresql,mariadb,sqlite,sqlserver,clickhouse,spanner)."`
-	flow             flowType      `hidden:"" help:"Workflow to initialize (versioned, declarative)."`
-	stdin            io.ReadCloser `hidden:""`
-	cloudURL         string        `hidden:""`
-	cloudRepo        string        `hidden:""`
-	env              gen.Env       `hidden:""`
+	From             string            `optional:"" help:"URL of the current schema state."`
+	To               string            `optional:"" help:"URL of the desired schema state."`
+	DirPath          string            `arg:"" optional:"" type:"-path" help:"Path inside repository containing the migration files."`
+	Token            string            `short:"t" env:"ATLAS_CLOUD_TOKEN" help:"Atlas authentication token."`
+	CloudURL         string            `name:"cloud-url" env:"ATLAS_CLOUD_URL" placeholder:"URL" help:"URL of the Atlas Cloud API, defaults to ${cloud_url}."`
+	Repo             string            `short:"R" help:"GitHub repository <owner>/<name>, defaults to the current repository."`
+	ConfigPath       string            `optional:"" help:"Path to atlas.hcl configuration file."`
+	ConfigEnv        string            `optional:"" help:"The environment to use from the Atlas configuration file."`
+	SchemaScope      bool              `optional:"" help:"Limit the scope of the work done by Atlas (inspection, diffing, etc.) to one schema."`
+	DirName          string            `optional:"" help:"Name of target migration directory in Atlas Cloud."`
+	Replace          bool              `optional:"" help:"Replace existing Atlas CI workflow."`
+	SetupSchemaApply *bool             `name:"schema-apply" help:"Whether to setup the 'schema apply' action."`
+	Offline          bool              `help:"Generate a workflow that lints migrations without Atlas Cloud (alias: --no-cloud)."`
+	NoCloud          bool              `name:"no-cloud" hidden:"" help:"Alias of --offline."`
+	RunsOn           []string          `name:"runs-on" placeholder:"LABEL" help:"Labels of the runner of the workflow jobs, defaults to ubuntu-latest."`
+	Container        string            `placeholder:"IMAGE" help:"Container image to run the workflow jobs in."`
+	TimeoutMinutes   int               `name:"timeout-minutes" help:"Maximum number of minutes the workflow jobs can run."`
+	DevDB            string            `name:"dev-db" placeholder:"MODE" help:"How the dev-database is provided to the actions (service, docker, custom), defaults to service containers on Linux runners and docker otherwise."`
+	DevURLSecret     string            `name:"dev-url-secret" default:"ATLAS_DEV_URL" help:"Name of the secret holding the dev-database URL, used with --dev-db=custom."`
+	ActionMirrors    map[string]string `name:"action-mirror" placeholder:"REPO=MIRROR" help:"Mirror of an action repository on GitHub Enterprise Server, e.g. ariga/atlas-action=actions-mirror/atlas-action."`
+	RequireCheck     bool              `name:"require-check" help:"Require the Atlas check for merging into the default branch, by updating its branch protection or rulesets."`
+	PinActions       bool              `name:"pin-actions" help:"Pin the actions used by the workflow to commit SHAs."`
+	TriggerBranches  []string          `name:"trigger-branch" help:"Additional branch that runs the workflow on push."`
+	TriggerPaths     []string          `name:"trigger-path" help:"Path filter for the push and pull_request events, replaces the filters derived from the config."`
+	MergeGroup       *bool             `name:"merge-group" negatable:"" help:"Run the workflow on merge queue checks, detected from the rulesets of the default branch if not set."`
+	DispatchInputs   []string          `name:"dispatch-input" placeholder:"NAME[:TYPE][=DEFAULT]" help:"Input of the workflow_dispatch event."`
+	PRTitle          string            `name:"pr-title" placeholder:"TEMPLATE" help:"Title of the pull request, a Go template executed with the workflow config."`
+	PRBody           string            `name:"pr-body" placeholder:"TEMPLATE" help:"Body of the pull request, a Go template executed with the workflow config."`
+	PRTemplate       string            `name:"pr-template" type:"path" placeholder:"FILE" help:"File with the title (first line) and body templates of the pull request, overridden by --pr-title and --pr-body."`
+	Labels           []string          `name:"label" help:"Label to add to the pull request."`
+	Reviewers        []string          `name:"reviewer" placeholder:"LOGIN" help:"User or <org>/<team> to request a review of the pull request from."`
+	Assignees        []string          `name:"assignee" placeholder:"LOGIN" help:"User to assign the pull request to."`
+	Draft            bool              `help:"Create the pull request as a draft."`
+	NewPR            bool              `name:"new-pr" help:"Create a new pull request even if an open pull request created by init-action exists."`
+	Branch           string            `placeholder:"NAME" help:"Name of the branch to create, defaults to a random atlas-ci-<suffix> name."`
+	SecretName       string            `name:"secret-name" placeholder:"NAME" help:"Name of the secret to store the Atlas Cloud token in, defaults to a random ATLAS_CLOUD_TOKEN_<suffix> name."`
+	KeepOnFailure    bool              `name:"keep-on-failure" help:"Keep the secret and the branch created by the command if it fails, instead of deleting them."`
+	driver           string            `hidden:"" help:"Driver of the migration directory (mysql,postgresql,mariadb,sqlite,sqlserver,clickhouse,spanner)."`
+	flow             flowType          `hidden:"" help:"Workflow to initialize (versioned, declarative)."`
+	stdin            io.ReadCloser     `hidden:""`
+	stdout           io.Writer         `hidden:""`
+	stderr           io.Writer         `hidden:""`
+	cloudRepo        string            `hidden:""`
+	atlasRepo        *cloudapi.Repo    `hidden:""`
+	env              gen.Env           `hidden:""`
+	tokenSources     []tokenSource     `hidden:""`
+	naming           naming            `hidden:""`
+	outputFlags
 }
 
 func (i *InitActionCmd) Help() string {
-	return `Examples:
+	return `The Atlas Cloud token is taken from the first of the following that is set:
+	1. The --token flag.
+	2. The ATLAS_CLOUD_TOKEN environment variable.
+	3. The credentials file created by "atlas login" (~/.atlas/credentials.json),
+	   used only with the default Atlas Cloud URL.
+	4. An interactive prompt.
+
+Examples:
 	gh atlas init-action
 	gh atlas init-action --token=$ATLAS_CLOUD_TOKEN
-	gh atlas init-action --token=$ATLAS_CLOUD_TOKEN --dir-name="migrations" "dir/migrations"`
+	gh atlas init-action --token=$ATLAS_CLOUD_TOKEN --dir-name="migrations" "dir/migrations"
+	gh atlas init-action --cloud-url="https://api.eu.atlasgo.cloud/query"
+	gh atlas init-action --offline "dir/migrations"
+	gh atlas init-action --pin-actions
+	gh atlas init-action --require-check
+	gh atlas init-action --runs-on=self-hosted --runs-on=linux --timeout-minutes=15
+	gh atlas init-action --dev-db=custom --dev-url-secret=DEV_DB_URL
+	gh atlas init-action --hostname=github.example.com --action-mirror=ariga/atlas-action=actions/atlas-action
+	gh atlas init-action --trigger-branch=release --merge-group --dispatch-input=dry-run:boolean=true
+	gh atlas init-action --token=$ATLAS_CLOUD_TOKEN --jq .pullRequest.url "dir/migrations"
+	gh atlas init-action --draft --label=database --reviewer=ariga/dba --pr-title="ci: lint {{ .Path }} with Atlas"
+	gh atlas init-action --pr-template=.github/atlas-pr.md
+
+The pull request templates are executed with the workflow config, e.g. {{ .Driver }},
+{{ .CheckName }} or {{ join .Branches ", " }}.`
 }
 
 const (
 	commitMsg = ".github/workflows: add atlas ci workflow"
 	prBody    = "PR created by the `gh atlas init-action` command.\n for more information visit https://github.com/ariga/gh-atlas."
+	// forkSecretNote is appended to the body of PRs opened from a fork.
+	forkSecretNote = "\n\n> **Note**\n> This PR was opened from a fork, and the `%s` secret used by the workflow was not created. " +
+		"A maintainer needs to add it to the repository secrets with an Atlas Cloud token before merging."
 )
 
 // Run the init-action command.
-func (i *InitActionCmd) Run(ctx context.Context, client *githubClient, current repository.Repository) error {
+func (i *InitActionCmd) Run(ctx context.Context, client *githubClient, current repository.Repository) (err error) {
 	var (
-		err        error
-		randSuffix = randSeq(6)
-		branchName = "atlas-ci-" + randSuffix
-		secretName = "ATLAS_CLOUD_TOKEN_" + randSuffix
+		branchName string
+		secretName string
+		changes    journal
 	)
+	defer func() {
+		if err == nil || i.KeepOnFailure {
+			return
+		}
+		// Undo the changes even if the command was canceled.
+		if rerr := changes.rollback(context.WithoutCancel(ctx), i.out()); rerr != nil {
+			err = errors.Join(err, rerr)
+		}
+	}()
+	i.Offline = i.Offline || i.NoCloud
 	// validate params set by flags
 	if err := i.validateParams(); err != nil {
 		return err
 	}
+	triggers, err := i.triggers()
+	if err != nil {
+		return err
+	}
+	prTmpl, err := i.prTemplate()
+	if err != nil {
+		return err
+	}
 	if i.Repo != "" {
 		current, err = repository.Parse(i.Repo)
 		if err != nil {
@@ -105,12 +176,16 @@ func (i *InitActionCmd) Run(ctx context.Context, client *githubClient, current r
 		return err
 	}
 	repo := NewRepository(client, current, repoData.GetDefaultBranch())
-	if err = i.setToken(); err != nil {
-		return err
-	}
-	cloud := cloudapi.New(i.cloudURL, i.Token)
-	if err = cloud.ValidateToken(ctx); err != nil {
-		return errors.New("the given Atlas token is invalid, please generate a new one and try again")
+	var cloud cloudapi.API
+	if !i.Offline {
+		if err = i.setToken(); err != nil {
+			return err
+		}
+		c := cloudapi.New(i.CloudURL, i.Token)
+		if err = c.ValidateToken(ctx); err != nil {
+			return tokenError(err, c.Endpoint())
+		}
+		cloud = c
 	}
 	// inherit in case config is set by flags
 	i.env.Path = i.ConfigPath
@@ -118,52 +193,274 @@ func (i *InitActionCmd) Run(ctx context.Context, client *githubClient, current r
 	if err = i.setParams(ctx, repo, cloud); err != nil {
 		return err
 	}
-	if err = repo.SetSecret(ctx, secretName, i.Token); err != nil {
-		return err
+	if i.MergeGroup == nil {
+		// Failing to read the rulesets, e.g., on GitHub Enterprise
+		// versions without rulesets, is treated as no merge queue.
+		if ok, err := repo.HasMergeQueue(ctx); err == nil && ok {
+			triggers.MergeGroup = true
+			fmt.Fprintf(i.out(), "%s %s %s\n",
+				promptui.IconGood,
+				promptui.Styler(promptui.FGFaint)("Merge queue detected on branch:"),
+				repo.defaultBranch,
+			)
+		}
 	}
-	if err = repo.CheckoutNewBranch(ctx, branchName); err != nil {
-		return err
+	var fork string
+	if !canPush(repoData) {
+		if fork, err = repo.UseFork(ctx); err != nil {
+			return err
+		}
+		fmt.Fprintf(i.out(), "%s %s %s\n",
+			promptui.IconGood,
+			promptui.Styler(promptui.FGFaint)("No push access to the repository, using fork:"),
+			fork,
+		)
+	}
+	var existing *github.PullRequest
+	if !i.NewPR {
+		if existing, err = repo.FindPR(ctx); err != nil {
+			return err
+		}
+	}
+	if existing != nil && i.Branch != "" && existing.GetHead().GetRef() != i.Branch {
+		// A different branch was requested.
+		existing = nil
+	}
+	if existing == nil {
+		// Secrets cannot be read from forks, and are not created for offline workflows.
+		if branchName, secretName, err = i.names(ctx, repo, fork == "" && !i.Offline); err != nil {
+			return err
+		}
+	} else {
+		// Reuse the branch and the secret of the existing PR.
+		if branchName, secretName, err = i.existingNames(ctx, repo, existing); err != nil {
+			return err
+		}
+		fmt.Fprintf(i.out(), "%s %s %s\n",
+			promptui.IconGood,
+			promptui.Styler(promptui.FGFaint)("Updating existing PR (use --new-pr to create a new one):"),
+			existing.GetHTMLURL(),
+		)
+	}
+	if i.Offline {
+		// The workflow does not use Atlas Cloud.
+		secretName = ""
 	}
+	// The pull request is rendered before changing the repository, as its templates may
+	// fail to execute. Only the fork, which the branch names depend on, is created before.
 	cfg := &gen.Config{
-		Flow:          string(i.flow),
-		From:          i.From,
-		To:            i.To,
-		Path:          i.DirPath,
-		DirName:       i.DirName,
-		Driver:        i.driver,
-		SecretName:    secretName,
-		DefaultBranch: repo.defaultBranch,
-		Env:           i.env,
-		SchemaScope:   i.SchemaScope,
-		CloudRepo:     i.cloudRepo,
+		Flow:           string(i.flow),
+		From:           i.From,
+		To:             i.To,
+		Path:           i.DirPath,
+		DirName:        i.DirName,
+		Driver:         i.driver,
+		SecretName:     secretName,
+		DefaultBranch:  repo.defaultBranch,
+		Env:            i.env,
+		SchemaScope:    i.SchemaScope,
+		CloudRepo:      i.cloudRepo,
+		Offline:        i.Offline,
+		Triggers:       triggers,
+		RequiredCheck:  i.RequireCheck,
+		RunsOn:         i.RunsOn,
+		Container:      i.Container,
+		TimeoutMinutes: i.TimeoutMinutes,
+		DevDBMode:      i.DevDB,
+		DevURLSecret:   i.DevURLSecret,
+		Mirrors:        i.ActionMirrors,
+	}
+	if client.enterpriseServer() {
+		if len(cfg.RunsOn) == 0 {
+			// GitHub-hosted runners are not available on GitHub Enterprise Server.
+			cfg.RunsOn = []string{"self-hosted"}
+		}
+		missing, err := repo.MissingActions(ctx, cfg)
+		if err != nil {
+			return err
+		}
+		if len(missing) > 0 {
+			fmt.Fprintf(i.out(), "%s The following actions were not found on %s: %s. They can be used only if GitHub Connect is enabled, otherwise mirror them and use --action-mirror.\n",
+				promptui.IconWarn, client.Host, strings.Join(missing, ", "))
+		}
 	}
 	if i.flow == "declarative" && i.SetupSchemaApply != nil {
 		cfg.SetupSchemaApply = *i.SetupSchemaApply
 	}
-	if err = repo.AddAtlasYAML(ctx, cfg, branchName, commitMsg, i.Replace); err != nil {
-		return err
+	if i.PinActions {
+		if err = repo.PinActions(ctx, cfg); err != nil {
+			return err
+		}
 	}
-	link, err := repo.CreatePR(ctx, commitMsg, prBody, branchName)
+	prOpts, err := i.pullRequest(prTmpl, cfg)
 	if err != nil {
 		return err
 	}
-	fmt.Printf("%s %s %s\n",
-		promptui.IconGood,
-		promptui.Styler(promptui.FGFaint)("Created PR:"),
-		link,
-	)
-	if err = i.openURL(link); err != nil {
-		fmt.Printf("Failed to open %s in browser: %v\n", link, err)
+	if fork != "" && secretName != "" {
+		prOpts.Body += fmt.Sprintf(forkSecretNote, secretName)
+	}
+	switch {
+	case i.Offline:
+		// No token to store.
+	case fork != "":
+		// Secrets can be created only by users with write access, and
+		// the token should not be shared with the maintainers anyway.
+		fmt.Fprintf(i.out(), "%s The %q secret was not created, a maintainer of %s/%s needs to create it with an Atlas Cloud token before merging the PR.\n",
+			promptui.IconWarn, secretName, current.Owner(), current.Name())
+	default:
+		if err = repo.SetSecret(ctx, secretName, i.Token, existing != nil); err != nil {
+			return err
+		}
+		// The secret of an existing PR was already there, and its previous value is unknown.
+		if existing == nil {
+			name := secretName
+			changes.record(fmt.Sprintf("secret %q", name), func(ctx context.Context) error {
+				return repo.DeleteSecret(ctx, name)
+			})
+		}
+	}
+	if existing == nil {
+		if err = repo.CheckoutNewBranch(ctx, branchName); err != nil {
+			return err
+		}
+		// Deleting the branch also discards the workflow committed to it.
+		name := branchName
+		changes.record(fmt.Sprintf("branch %q", name), func(ctx context.Context) error {
+			return repo.DeleteBranch(ctx, name)
+		})
+	}
+	var pr *github.PullRequest
+	if existing != nil {
+		changed, err := repo.UpdateAtlasYAML(ctx, cfg, branchName, commitMsg)
+		if err != nil {
+			return err
+		}
+		if !changed {
+			fmt.Fprintf(i.out(), "%s %s\n",
+				promptui.IconGood,
+				promptui.Styler(promptui.FGFaint)("Workflow is up to date"),
+			)
+		}
+		if pr, err = repo.EditPR(ctx, existing.GetNumber(), prOpts); err != nil {
+			return err
+		}
+		fmt.Fprintf(i.out(), "%s %s %s\n",
+			promptui.IconGood,
+			promptui.Styler(promptui.FGFaint)("Updated PR:"),
+			pr.GetHTMLURL(),
+		)
+	} else {
+		if err = repo.AddAtlasYAML(ctx, cfg, branchName, commitMsg, i.Replace); err != nil {
+			return err
+		}
+		if pr, err = repo.CreatePR(ctx, branchName, prOpts); err != nil {
+			return err
+		}
+		fmt.Fprintf(i.out(), "%s %s %s\n",
+			promptui.IconGood,
+			promptui.Styler(promptui.FGFaint)("Created PR:"),
+			pr.GetHTMLURL(),
+		)
+	}
+	// The changes are referenced by the PR, and are kept from now on.
+	changes.commit()
+	// The PR was already created, failing to update its metadata is not fatal.
+	if err = repo.UpdatePRMetadata(ctx, pr.GetNumber(), prOpts); err != nil {
+		fmt.Fprintf(i.out(), "%s Failed to update the PR: %v\n", promptui.IconWarn, err)
+	}
+	res := &initActionResult{
+		Branch:       branchName,
+		Fork:         fork,
+		SecretName:   secretName,
+		WorkflowPath: workflowPath,
+		PullRequest:  pullRequestResult{Number: pr.GetNumber(), URL: pr.GetHTMLURL(), Updated: existing != nil},
+		CloudRepo:    i.atlasRepo,
+		Config:       cfg,
+	}
+	if i.RequireCheck {
+		// The PR was already created, failing to require the check is not fatal.
+		if res.RequiredCheck, err = requireCheck(ctx, i.out(), repo, cfg.CheckName()); err != nil {
+			fmt.Fprintf(i.out(), "%s Failed to require the %q check: %v\n", promptui.IconWarn, cfg.CheckName(), err)
+		} else if _, err := repo.ReadContent(ctx, workflowPath); err != nil {
+			// Pull requests run the workflow of their branch, which does not have it yet.
+			fmt.Fprintf(i.out(), "%s Until the PR is merged, other pull requests to %s do not report the %q check, and cannot be merged before they are updated with %s.\n",
+				promptui.IconWarn, repo.defaultBranch, cfg.CheckName(), repo.defaultBranch)
+		}
+	} else {
+		fmt.Fprintf(i.out(), "%s %s\n",
+			promptui.IconWarn,
+			promptui.Styler(promptui.FGFaint)("Run `gh atlas require-check` after merging the PR to require the Atlas check."),
+		)
+	}
+	if i.enabled() {
+		return i.print(i.result(), res)
+	}
+	if err = i.openURL(pr.GetHTMLURL()); err != nil {
+		fmt.Fprintf(i.out(), "Failed to open %s in browser: %v\n", pr.GetHTMLURL(), err)
 	}
 	return nil
 }
 
-var letters = []rune("0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ")
+type (
+	// initActionResult is the result of the init-action command printed in JSON mode.
+	initActionResult struct {
+		Branch string `json:"branch"`
+		// Fork is the repository the branch was pushed to, if the user cannot push to the repository.
+		Fork string `json:"fork,omitempty"`
+		// SecretName is empty for offline workflows.
+		SecretName    string            `json:"secretName,omitempty"`
+		WorkflowPath  string            `json:"workflowPath"`
+		PullRequest   pullRequestResult `json:"pullRequest"`
+		CloudRepo     *cloudapi.Repo    `json:"cloudRepo,omitempty"`
+		RequiredCheck *checkResult      `json:"requiredCheck,omitempty"`
+		Config        *gen.Config       `json:"config"`
+	}
+	// pullRequestResult identifies the pull request created by the init-action command.
+	pullRequestResult struct {
+		Number int    `json:"number"`
+		URL    string `json:"url"`
+		// Updated is true if an existing pull request was updated.
+		Updated bool `json:"updated,omitempty"`
+	}
+)
+
+// out returns the writer for the progress messages. In JSON mode, they are
+// written to stderr to keep the output of the command machine-readable.
+func (i *InitActionCmd) out() io.Writer {
+	if i.enabled() {
+		if i.stderr != nil {
+			return i.stderr
+		}
+		return os.Stderr
+	}
+	return i.result()
+}
+
+// result returns the writer for the result of the command.
+func (i *InitActionCmd) result() io.Writer {
+	if i.stdout != nil {
+		return i.stdout
+	}
+	return os.Stdout
+}
 
-func randSeq(n int) string {
-	b := make([]rune, n)
-	for i := range b {
-		b[i] = letters[rand.Intn(len(letters))]
+// tokenError returns an actionable message for a failed token validation.
+func tokenError(err error, endpoint string) error {
+	var rl *cloudapi.RateLimitError
+	switch {
+	case errors.Is(err, cloudapi.ErrUnauthenticated):
+		return errors.New("the given Atlas token is invalid, please generate a new one and try again")
+	case errors.Is(err, cloudapi.ErrForbidden):
+		return errors.New("the given Atlas token is not allowed to access Atlas Cloud, make sure it was generated for the right organization")
+	case errors.Is(err, cloudapi.ErrNotFound):
+		return fmt.Errorf("the Atlas Cloud API was not found at %q, make sure the Atlas Cloud URL is correct", endpoint)
+	case errors.Is(err, cloudapi.ErrNetwork):
+		return fmt.Errorf("failed to connect to Atlas Cloud at %q, check your network connection and the Atlas Cloud URL: %w", endpoint, err)
+	case errors.Is(err, cloudapi.ErrServer):
+		return fmt.Errorf("Atlas Cloud is currently unavailable, please try again later: %w", err)
+	case errors.As(err, &rl):
+		return errors.New("Atlas Cloud rate limit exceeded, please try again later")
+	default:
+		return fmt.Errorf("failed to validate the Atlas token: %w", err)
 	}
-	return string(b)
 }
@@ -1,12 +1,17 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 
@@ -22,33 +27,87 @@ type mockService struct {
 	getContentError error
 	hasHclFile      bool
 	hclFileContent  string
+	workflowContent string // content of the workflow on the branches, if set
+	permissions     map[string]bool
+	branches        []string // existing branches, in addition to master
+	secrets         []string // existing secrets
+	failOn          string   // method that fails, if set
+	deleted         []string // deleted refs and secrets
 }
 
-func (m *mockService) GetRef(context.Context, string, string, string) (*github.Reference, *github.Response, error) {
-	ref := &github.Reference{
-		Object: &github.GitObject{
-			SHA: nil,
-		},
+// fail returns an error if the method is set to fail.
+func (m *mockService) fail(method string) error {
+	if m.failOn == method {
+		return fmt.Errorf("%s failed", method)
 	}
-	return ref, nil, nil
+	return nil
 }
-func (m *mockService) CreateRef(context.Context, string, string, *github.Reference) (*github.Reference, *github.Response, error) {
+
+func (m *mockService) GetRef(_ context.Context, _, _, ref string) (*github.Reference, *github.Response, error) {
+	if ref != "refs/heads/master" && !slices.Contains(m.branches, strings.TrimPrefix(ref, "refs/heads/")) {
+		res := &github.Response{Response: &http.Response{StatusCode: http.StatusNotFound}}
+		return nil, res, &github.ErrorResponse{Response: res.Response, Message: "Not Found"}
+	}
+	return &github.Reference{Object: &github.GitObject{}}, nil, nil
+}
+func (m *mockService) GetTag(context.Context, string, string, string) (*github.Tag, *github.Response, error) {
 	return nil, nil, nil
 }
-func (m *mockService) Get(context.Context, string, string) (*github.Repository, *github.Response, error) {
+func (m *mockService) ListMatchingRefs(context.Context, string, string, *github.ReferenceListOptions) ([]*github.Reference, *github.Response, error) {
+	return nil, nil, nil
+}
+func (m *mockService) GetRulesForBranch(context.Context, string, string, string) ([]*repositoryRule, *github.Response, error) {
+	return nil, nil, nil
+}
+func (m *mockService) CreateRuleset(context.Context, string, string, *ruleset) (*ruleset, *github.Response, error) {
 	return nil, nil, nil
 }
+func (m *mockService) GetBranchProtection(context.Context, string, string, string) (*github.Protection, *github.Response, error) {
+	return nil, nil, github.ErrBranchNotProtected
+}
+func (m *mockService) UpdateRequiredStatusChecks(context.Context, string, string, string, *github.RequiredStatusChecksRequest) (*github.RequiredStatusChecks, *github.Response, error) {
+	return nil, nil, nil
+}
+func (m *mockService) CreateRef(context.Context, string, string, *github.Reference) (*github.Reference, *github.Response, error) {
+	return nil, nil, m.fail("CreateRef")
+}
+func (m *mockService) DeleteRef(_ context.Context, _, _, ref string) (*github.Response, error) {
+	if err := m.fail("DeleteRef"); err != nil {
+		return nil, err
+	}
+	m.deleted = append(m.deleted, ref)
+	return nil, nil
+}
+func (m *mockService) DeleteRepoSecret(_ context.Context, _, _, name string) (*github.Response, error) {
+	if err := m.fail("DeleteRepoSecret"); err != nil {
+		return nil, err
+	}
+	m.deleted = append(m.deleted, "secret:"+name)
+	return nil, nil
+}
+func (m *mockService) Get(context.Context, string, string) (*github.Repository, *github.Response, error) {
+	return &github.Repository{DefaultBranch: github.String("master"), Permissions: m.permissions}, nil, nil
+}
+func (m *mockService) CreateFork(context.Context, string, string, *github.RepositoryCreateForkOptions) (*github.Repository, *github.Response, error) {
+	return &github.Repository{Name: github.String("repo"), Owner: &github.User{Login: github.String("octocat")}}, nil, nil
+}
 func (m *mockService) GetContents(ctx context.Context, owner string, repo string, path string, opts *github.RepositoryContentGetOptions) (*github.RepositoryContent, []*github.RepositoryContent, *github.Response, error) {
 	if path == "atlas.hcl" {
 		return &github.RepositoryContent{Content: &m.hclFileContent}, nil, nil, nil
 	}
+	if path == workflowPath && m.workflowContent != "" {
+		return &github.RepositoryContent{Content: &m.workflowContent}, nil, nil, nil
+	}
 	sha := "12345"
 	return &github.RepositoryContent{SHA: &sha}, nil, nil, m.getContentError
 }
 func (m *mockService) CreateFile(context.Context, string, string, string, *github.RepositoryContentFileOptions) (*github.RepositoryContentResponse, *github.Response, error) {
-	return nil, nil, nil
+	return nil, nil, m.fail("CreateFile")
 }
-func (m *mockService) GetRepoSecret(context.Context, string, string, string) (*github.Secret, *github.Response, error) {
+func (m *mockService) GetRepoSecret(_ context.Context, _, _, name string) (*github.Secret, *github.Response, error) {
+	if slices.Contains(m.secrets, name) {
+		return &github.Secret{Name: name}, &github.Response{Response: &http.Response{StatusCode: http.StatusOK}}, nil
+	}
 	res := &github.Response{
 		Response: &http.Response{
 			StatusCode: http.StatusNotFound,
@@ -62,14 +121,74 @@ func (m *mockService) CreateOrUpdateRepoSecret(context.Context, string, string,
 			StatusCode: http.StatusOK,
 		},
 	}
-	return res, nil
+	return res, m.fail("CreateOrUpdateRepoSecret")
 }
 func (m *mockService) GetRepoPublicKey(context.Context, string, string) (*github.PublicKey, *github.Response, error) {
 	return nil, nil, nil
 }
-func (m *mockService) Create(context.Context, string, string, *github.NewPullRequest) (*github.PullRequest, *github.Response, error) {
+
+// mockPullRequestsService is a mock implementation of the GitHub pull requests API.
+type mockPullRequestsService struct {
+	createErr error
+	prs       []*github.PullRequest
+	created   *github.NewPullRequest
+	edited    *github.PullRequest
+	reviewers github.ReviewersRequest
+}
+
+func (m *mockPullRequestsService) Create(_ context.Context, _, _ string, pr *github.NewPullRequest) (*github.PullRequest, *github.Response, error) {
+	if m.createErr != nil {
+		return nil, nil, m.createErr
+	}
+	m.created = pr
+	return &github.PullRequest{
+		Number:  github.Int(42),
+		HTMLURL: github.String("https://github.com/owner/repo/pull/42"),
+	}, nil, nil
+}
+func (m *mockPullRequestsService) Get(_ context.Context, _, _ string, number int) (*github.PullRequest, *github.Response, error) {
+	for _, pr := range m.prs {
+		if pr.GetNumber() == number {
+			return pr, nil, nil
+		}
+	}
+	return nil, nil, errors.New("pull request not found")
+}
+func (m *mockPullRequestsService) List(_ context.Context, _, _ string, opts *github.PullRequestListOptions) ([]*github.PullRequest, *github.Response, error) {
+	var prs []*github.PullRequest
+	for _, pr := range m.prs {
+		if opts.Head == "" || pr.GetHead().GetLabel() == opts.Head {
+			prs = append(prs, pr)
+		}
+	}
+	return prs, nil, nil
+}
+func (m *mockPullRequestsService) Edit(_ context.Context, _, _ string, number int, pr *github.PullRequest) (*github.PullRequest, *github.Response, error) {
+	m.edited = pr
+	return &github.PullRequest{
+		Number:  github.Int(number),
+		HTMLURL: github.String(fmt.Sprintf("https://github.com/owner/repo/pull/%d", number)),
+	}, nil, nil
+}
+func (m *mockPullRequestsService) RequestReviewers(_ context.Context, _, _ string, _ int, reviewers github.ReviewersRequest) (*github.PullRequest, *github.Response, error) {
+	m.reviewers = reviewers
 	return nil, nil, nil
 }
+
+// mockIssuesService is a mock implementation of the GitHub issues API.
+type mockIssuesService struct {
+	labels, assignees []string
+	err               error
+}
+
+func (m *mockIssuesService) AddLabelsToIssue(_ context.Context, _, _ string, _ int, labels []string) ([]*github.Label, *github.Response, error) {
+	m.labels = labels
+	return nil, nil, m.err
+}
+func (m *mockIssuesService) AddAssignees(_ context.Context, _, _ string, _ int, assignees []string) (*github.Issue, *github.Response, error) {
+	m.assignees = assignees
+	return nil, nil, m.err
+}
 func (m *mockService) GetTree(context.Context, string, string, string, bool) (*github.Tree, *github.Response, error) {
 	tree := &github.Tree{
 		Entries: []*github.TreeEntry{
@@ -112,7 +231,10 @@ func createGHClient(repoSvc repositoriesService, gitSvc gitService) *githubClien
 		Git:          gitSvc,
 		Repositories: repoSvc,
 		Actions:      &mockService{},
-		PullRequests: &mockService{},
+		PullRequests: &mockPullRequestsService{},
+		Issues:       &mockIssuesService{},
+		Rulesets:     &mockService{},
+		Protection:   &mockService{},
 	}
 }
 
@@ -166,6 +288,7 @@ func TestRunInitActionCmd(t *testing.T) {
 					Repos struct {
 						Repos []cloudapi.Repo `json:"repos"`
 					} `json:"repos"`
+					TokenInfo *cloudapi.TokenInfo `json:"tokenInfo,omitempty"`
 				} `json:"data"`
 			}
 		)
@@ -177,7 +300,12 @@ func TestRunInitActionCmd(t *testing.T) {
 				return
 			}
 		}
-		if strings.Contains(input.Query, "repos") {
+		if strings.Contains(input.Query, "tokenInfo") {
+			payload.Data.TokenInfo = &cloudapi.TokenInfo{
+				Type:   cloudapi.BotToken,
+				Scopes: []string{cloudapi.ScopeMigratePush, cloudapi.ScopeSchemaPush},
+			}
+		} else if strings.Contains(input.Query, "repos") {
 			token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
 			require.True(t, ok)
 			payload.Data.Repos.Repos, ok = reposByToken[token]
@@ -552,6 +680,85 @@ func TestRunInitActionCmd(t *testing.T) {
 				SchemaScope: false,
 			},
 		},
+		{
+			name: "offline mode, no token required",
+			cmd: &InitActionCmd{
+				Offline:     true,
+				DirPath:     "migrations",
+				driver:      "MYSQL",
+				SchemaScope: true,
+			},
+			expected: &InitActionCmd{
+				DirPath:     "migrations",
+				driver:      "MYSQL",
+				SchemaScope: true,
+			},
+		},
+		{
+			name: "offline mode, choose dir path and driver from repo",
+			cmd: &InitActionCmd{
+				NoCloud:     true,
+				SchemaScope: true,
+			},
+			// enter (migrations dir), enter (MYSQL driver)
+			prompt: "\n\n",
+			expected: &InitActionCmd{
+				DirPath:     "migrations",
+				driver:      "MYSQL",
+				SchemaScope: true,
+			},
+		},
+		{
+			name: "offline mode with dir name",
+			cmd: &InitActionCmd{
+				Offline: true,
+				DirPath: "migrations",
+				DirName: "name",
+				driver:  "MYSQL",
+			},
+			wantErr: true,
+		},
+		{
+			name: "offline mode with declarative flow",
+			cmd: &InitActionCmd{
+				Offline: true,
+				To:      "file://schema.hcl",
+				driver:  "MYSQL",
+			},
+			wantErr: true,
+		},
+		{
+			name: "custom dev-db, no driver required",
+			cmd: &InitActionCmd{
+				Offline:      true,
+				DirPath:      "migrations",
+				DevDB:        "custom",
+				DevURLSecret: "DEV_URL",
+			},
+			expected: &InitActionCmd{
+				DirPath: "migrations",
+			},
+		},
+		{
+			name: "invalid dev-db",
+			cmd: &InitActionCmd{
+				Offline: true,
+				DirPath: "migrations",
+				driver:  "MYSQL",
+				DevDB:   "remote",
+			},
+			wantErr: true,
+		},
+		{
+			name: "custom dev-db without secret",
+			cmd: &InitActionCmd{
+				Offline: true,
+				DirPath: "migrations",
+				driver:  "MYSQL",
+				DevDB:   "custom",
+			},
+			wantErr: true,
+		},
 		/*
 			+------------------------+
 			|    declarative flow    |
@@ -672,7 +879,7 @@ func TestRunInitActionCmd(t *testing.T) {
 				err = w.Close()
 				require.NoError(t, err)
 				tt.cmd.stdin = &stdinBuffer{r}
-				tt.cmd.cloudURL = srv.URL
+				tt.cmd.CloudURL = srv.URL
 
 				if tt.client == nil {
 					tt.client = defaultClient
@@ -689,6 +896,240 @@ func TestRunInitActionCmd(t *testing.T) {
 	}
 }
 
+func TestRunInitActionCmd_JSON(t *testing.T) {
+	repo, err := repository.Parse("owner/repo")
+	require.NoError(t, err)
+	var (
+		stdout, stderr bytes.Buffer
+		client         = createGHClient(&mockService{getContentError: &github.ErrorResponse{Message: "Not Found"}}, &mockService{})
+	)
+	cmd := &InitActionCmd{
+		Offline:     true,
+		DirPath:     "migrations",
+		driver:      "MYSQL",
+		SchemaScope: true,
+		outputFlags: outputFlags{JSON: true},
+		stdin:       &stdinBuffer{strings.NewReader("")},
+		stdout:      &stdout,
+		stderr:      &stderr,
+	}
+	require.NoError(t, cmd.Run(context.Background(), client, repo), stderr.String())
+	var res struct {
+		Branch       string `json:"branch"`
+		SecretName   string `json:"secretName"`
+		WorkflowPath string `json:"workflowPath"`
+		PullRequest  struct {
+			Number int    `json:"number"`
+			URL    string `json:"url"`
+		} `json:"pullRequest"`
+		Config gen.Config `json:"config"`
+	}
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &res))
+	require.True(t, strings.HasPrefix(res.Branch, "atlas-ci-"))
+	require.Empty(t, res.SecretName)
+	require.Equal(t, ".github/workflows/ci-atlas.yaml", res.WorkflowPath)
+	require.Equal(t, 42, res.PullRequest.Number)
+	require.Equal(t, "https://github.com/owner/repo/pull/42", res.PullRequest.URL)
+	require.Equal(t, "migrations", res.Config.Path)
+	require.True(t, res.Config.Offline)
+	// Progress messages are written to stderr.
+	require.Contains(t, stderr.String(), "Created PR:")
+
+	stdout.Reset()
+	cmd.outputFlags = outputFlags{JQ: ".pullRequest.url"}
+	require.NoError(t, cmd.Run(context.Background(), client, repo), stderr.String())
+	require.Equal(t, "https://github.com/owner/repo/pull/42\n", stdout.String())
+}
+
+func TestRunInitActionCmd_PullRequest(t *testing.T) {
+	repo, err := repository.Parse("owner/repo")
+	require.NoError(t, err)
+	tmpl := filepath.Join(t.TempDir(), "pr.md")
+	require.NoError(t, os.WriteFile(tmpl, []byte("ci: lint {{ .Path }}\n\nLints the {{ .Driver }} migrations, required check: {{ .CheckName }}.\n"), 0644))
+	newCmd := func() (*InitActionCmd, *githubClient, *bytes.Buffer) {
+		var out bytes.Buffer
+		client := createGHClient(&mockService{getContentError: &github.ErrorResponse{Message: "Not Found"}}, &mockService{})
+		return &InitActionCmd{
+			Offline:     true,
+			DirPath:     "migrations",
+			driver:      "MYSQL",
+			SchemaScope: true,
+			stdin:       &stdinBuffer{strings.NewReader("")},
+			stdout:      &out,
+		}, client, &out
+	}
+
+	cmd, client, _ := newCmd()
+	require.NoError(t, cmd.Run(context.Background(), client, repo))
+	created := client.PullRequests.(*mockPullRequestsService).created
+	require.Equal(t, commitMsg, created.GetTitle())
+	require.Equal(t, prBody+"\n\n"+prMarker, created.GetBody())
+	require.False(t, created.GetDraft())
+
+	cmd, client, _ = newCmd()
+	cmd.PRTemplate = tmpl
+	cmd.Labels = []string{"database"}
+	cmd.Reviewers = []string{"octocat", "ariga/dba"}
+	cmd.Assignees = []string{"a8m"}
+	cmd.Draft = true
+	require.NoError(t, cmd.Run(context.Background(), client, repo))
+	prs := client.PullRequests.(*mockPullRequestsService)
+	require.Equal(t, "ci: lint migrations", prs.created.GetTitle())
+	require.Equal(t, "Lints the MYSQL migrations, required check: atlas.\n\n"+prMarker, prs.created.GetBody())
+	require.True(t, prs.created.GetDraft())
+	require.Equal(t, github.ReviewersRequest{Reviewers: []string{"octocat"}, TeamReviewers: []string{"dba"}}, prs.reviewers)
+	issues := client.Issues.(*mockIssuesService)
+	require.Equal(t, []string{"database"}, issues.labels)
+	require.Equal(t, []string{"a8m"}, issues.assignees)
+
+	// Flags take precedence over the template file.
+	cmd, client, _ = newCmd()
+	cmd.PRTemplate = tmpl
+	cmd.RunsOn = []string{"self-hosted", "linux"}
+	cmd.PRTitle = "Atlas CI on {{ join .RunsOn \", \" }}"
+	require.NoError(t, cmd.Run(context.Background(), client, repo))
+	prs = client.PullRequests.(*mockPullRequestsService)
+	require.Equal(t, "Atlas CI on self-hosted, linux", prs.created.GetTitle())
+	require.Equal(t, "Lints the MYSQL migrations, required check: atlas.\n\n"+prMarker, prs.created.GetBody())
+
+	// Failing to update the metadata of the created PR is not fatal.
+	cmd, client, out := newCmd()
+	cmd.Labels = []string{"database"}
+	client.Issues = &mockIssuesService{err: errors.New("label not allowed")}
+	require.NoError(t, cmd.Run(context.Background(), client, repo))
+	require.Contains(t, out.String(), "Failed to update the PR: failed to add labels: label not allowed")
+
+	// The PR is opened from a fork if the user cannot push to the repository.
+	cmd, client, out = newCmd()
+	cmd.Offline, cmd.Token, cmd.CloudURL = false, "token", fakeCloudURL(t)
+	client.Repositories = &mockService{getContentError: &github.ErrorResponse{Message: "Not Found"}, permissions: map[string]bool{"pull": true}}
+	require.NoError(t, cmd.Run(context.Background(), client, repo))
+	prs = client.PullRequests.(*mockPullRequestsService)
+	require.True(t, strings.HasPrefix(prs.created.GetHead(), "octocat:atlas-ci-"))
+	require.True(t, prs.created.GetMaintainerCanModify())
+	require.Contains(t, prs.created.GetBody(), "This PR was opened from a fork")
+	require.Contains(t, out.String(), "using fork:")
+	require.Contains(t, out.String(), "secret was not created")
+
+	cmd, client, _ = newCmd()
+	cmd.PRTitle = "{{ .Unknown }}"
+	require.ErrorContains(t, cmd.Run(context.Background(), client, repo), "failed to execute the pull request title")
+
+	cmd, client, _ = newCmd()
+	cmd.PRBody = "{{ .Path"
+	require.ErrorContains(t, cmd.Run(context.Background(), client, repo), "invalid pull request body")
+
+	cmd, client, _ = newCmd()
+	cmd.Reviewers = []string{"ariga/"}
+	require.EqualError(t, cmd.Run(context.Background(), client, repo), `invalid reviewer "ariga/", expected a user login or <org>/<team>`)
+}
+
+func TestRunInitActionCmd_ExistingPR(t *testing.T) {
+	repo, err := repository.Parse("owner/repo")
+	require.NoError(t, err)
+	newCmd := func(prs ...*github.PullRequest) (*InitActionCmd, *githubClient, *bytes.Buffer) {
+		var out bytes.Buffer
+		client := createGHClient(&mockService{getContentError: &github.ErrorResponse{Message: "Not Found"}}, &mockService{})
+		client.PullRequests = &mockPullRequestsService{prs: prs}
+		return &InitActionCmd{
+			Offline:     true,
+			DirPath:     "migrations",
+			driver:      "MYSQL",
+			SchemaScope: true,
+			stdin:       &stdinBuffer{strings.NewReader("")},
+			stdout:      &out,
+		}, client, &out
+	}
+	existing := &github.PullRequest{
+		Number:  github.Int(7),
+		HTMLURL: github.String("https://github.com/owner/repo/pull/7"),
+		Head:    &github.PullRequestBranch{Ref: github.String("atlas-ci-abc123"), Repo: &github.Repository{FullName: github.String("owner/repo")}},
+	}
+
+	cmd, client, out := newCmd(existing)
+	require.NoError(t, cmd.Run(context.Background(), client, repo))
+	prs := client.PullRequests.(*mockPullRequestsService)
+	require.Nil(t, prs.created)
+	require.Equal(t, commitMsg, prs.edited.GetTitle())
+	require.Equal(t, prBody+"\n\n"+prMarker, prs.edited.GetBody())
+	require.Contains(t, out.String(), "Updating existing PR (use --new-pr to create a new one):")
+	require.Contains(t, out.String(), "Updated PR:")
+
+	// PRs from other repositories, e.g. forks, or branches are ignored.
+	other := &github.PullRequest{
+		Number: github.Int(8),
+		Head:   &github.PullRequestBranch{Ref: github.String("atlas-ci-def456"), Repo: &github.Repository{FullName: github.String("octocat/repo")}},
+	}
+	unrelated := &github.PullRequest{
+		Number: github.Int(9),
+		Head:   &github.PullRequestBranch{Ref: github.String("feature"), Repo: &github.Repository{FullName: github.String("owner/repo")}},
+	}
+	cmd, client, _ = newCmd(other, unrelated)
+	require.NoError(t, cmd.Run(context.Background(), client, repo))
+	prs = client.PullRequests.(*mockPullRequestsService)
+	require.NotNil(t, prs.created)
+	require.Nil(t, prs.edited)
+
+	// PRs are found by the marker in their body.
+	marked := &github.PullRequest{
+		Number: github.Int(10),
+		Body:   github.String(markBody("Renamed branch")),
+		Head:   &github.PullRequestBranch{Ref: github.String("atlas"), Repo: &github.Repository{FullName: github.String("owner/repo")}},
+	}
+	cmd, client, _ = newCmd(unrelated, marked)
+	cmd.outputFlags = outputFlags{JQ: ".branch, .pullRequest.updated"}
+	require.NoError(t, cmd.Run(context.Background(), client, repo))
+	require.Equal(t, "atlas\ntrue\n", cmd.stdout.(*bytes.Buffer).String())
+
+	// The secret of PRs on branches named otherwise is read from their workflow.
+	cmd, client, _ = newCmd(marked)
+	cmd.Offline, cmd.Token, cmd.CloudURL = false, "token", fakeCloudURL(t)
+	cmd.outputFlags = outputFlags{JQ: ".secretName"}
+	client.Repositories = &mockService{workflowContent: "      - uses: ariga/setup-atlas@v0\n        with:\n          cloud-token: ${{ secrets.ATLAS_TOKEN }}\n"}
+	require.NoError(t, cmd.Run(context.Background(), client, repo))
+	require.Equal(t, "ATLAS_TOKEN\n", cmd.stdout.(*bytes.Buffer).String())
+
+	cmd, client, _ = newCmd(marked)
+	cmd.Offline, cmd.Token, cmd.CloudURL = false, "token", fakeCloudURL(t)
+	require.ErrorContains(t, cmd.Run(context.Background(), client, repo), "failed to read the secret name from the workflow of")
+
+	// Branches created with --branch=atlas-ci-* keep the secret named by their workflow,
+	// and fall back to the secret named after the branch if the workflow cannot be read.
+	custom := &github.PullRequest{
+		Number: github.Int(11),
+		Body:   github.String(markBody("Custom branch")),
+		Head:   &github.PullRequestBranch{Ref: github.String("atlas-ci-foo"), Repo: &github.Repository{FullName: github.String("owner/repo")}},
+	}
+	cmd, client, _ = newCmd(custom)
+	cmd.Offline, cmd.Token, cmd.CloudURL = false, "token", fakeCloudURL(t)
+	cmd.outputFlags = outputFlags{JQ: ".secretName"}
+	client.Repositories = &mockService{workflowContent: "          cloud-token: ${{ secrets.ATLAS_CLOUD_TOKEN_X1Y2Z3 }}\n"}
+	require.NoError(t, cmd.Run(context.Background(), client, repo))
+	require.Equal(t, "ATLAS_CLOUD_TOKEN_X1Y2Z3\n", cmd.stdout.(*bytes.Buffer).String())
+
+	cmd, client, _ = newCmd(custom)
+	cmd.Offline, cmd.Token, cmd.CloudURL = false, "token", fakeCloudURL(t)
+	cmd.outputFlags = outputFlags{JQ: ".secretName"}
+	require.NoError(t, cmd.Run(context.Background(), client, repo))
+	require.Equal(t, "ATLAS_CLOUD_TOKEN_foo\n", cmd.stdout.(*bytes.Buffer).String())
+
+	cmd, client, _ = newCmd(existing)
+	cmd.NewPR = true
+	require.NoError(t, cmd.Run(context.Background(), client, repo))
+	prs = client.PullRequests.(*mockPullRequestsService)
+	require.NotNil(t, prs.created)
+	require.Nil(t, prs.edited)
+}
+
+// fakeCloudURL returns the URL of an Atlas Cloud API with a single migration directory.
+func fakeCloudURL(t *testing.T) string {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"data":{"repos":{"repos":[{"slug":"app","type":"MIGRATION_DIRECTORY","driver":"MYSQL"}]},"tokenInfo":{"type":"BOT","scopes":["MIGRATE_PUSH"]}}}`)
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
 func requireCommandsEqual(t *testing.T, a, b *InitActionCmd) {
 	require.Equal(t, a.DirPath, b.DirPath, "DirPath mismatch")
 	require.Equal(t, a.DirName, b.DirName, "DirName mismatch")
@@ -702,3 +1143,42 @@ func requireCommandsEqual(t *testing.T, a, b *InitActionCmd) {
 	require.Equal(t, a.cloudRepo, b.cloudRepo, "cloudRepo mismatch")
 	require.Equal(t, a.SetupSchemaApply, b.SetupSchemaApply, "SetupSchemaApply mismatch")
 }
+
+func TestTokenError(t *testing.T) {
+	const endpoint = "https://atlas.example.com/query"
+	for _, tt := range []struct {
+		err  error
+		want string
+	}{
+		{
+			err:  &cloudapi.AuthError{StatusCode: http.StatusUnauthorized},
+			want: "the given Atlas token is invalid, please generate a new one and try again",
+		},
+		{
+			err:  &cloudapi.AuthError{StatusCode: http.StatusForbidden},
+			want: "the given Atlas token is not allowed to access Atlas Cloud, make sure it was generated for the right organization",
+		},
+		{
+			err:  &cloudapi.StatusError{StatusCode: http.StatusNotFound},
+			want: `the Atlas Cloud API was not found at "https://atlas.example.com/query", make sure the Atlas Cloud URL is correct`,
+		},
+		{
+			err:  &cloudapi.TransportError{Err: errors.New("connection refused")},
+			want: `failed to connect to Atlas Cloud at "https://atlas.example.com/query", check your network connection and the Atlas Cloud URL: request failed: connection refused`,
+		},
+		{
+			err:  &cloudapi.StatusError{StatusCode: http.StatusBadGateway, Body: "bad gateway"},
+			want: "Atlas Cloud is currently unavailable, please try again later: unexpected status code: 502: bad gateway",
+		},
+		{
+			err:  &cloudapi.RateLimitError{},
+			want: "Atlas Cloud rate limit exceeded, please try again later",
+		},
+		{
+			err:  errors.New("unexpected EOF"),
+			want: "failed to validate the Atlas token: unexpected EOF",
+		},
+	} {
+		require.EqualError(t, tokenError(tt.err, endpoint), tt.want)
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v49/github"
+)
+
+// Prefixes of the names of the branch and the secret created by init-action.
+const (
+	branchPrefix = "atlas-ci-"
+	secretPrefix = "ATLAS_CLOUD_TOKEN_"
+)
+
+// maxNameAttempts is the number of names tried before giving up on finding names that are not taken.
+const maxNameAttempts = 5
+
+type (
+	// naming is the strategy for naming the branch and the secret created by init-action.
+	naming interface {
+		// Names returns the names of the branch and the secret for the attempt, starting
+		// at zero. It is called again with the next attempt if one of the names is taken.
+		Names(attempt int) (branch, secret string)
+	}
+	// randomNaming names the branch and the secret with a random suffix,
+	// e.g., atlas-ci-1A2B3C and ATLAS_CLOUD_TOKEN_1A2B3C.
+	randomNaming struct {
+		rand *rand.Rand
+	}
+)
+
+// newRandomNaming returns a randomNaming reading from the source.
+func newRandomNaming(src rand.Source) *randomNaming {
+	return &randomNaming{rand: rand.New(src)}
+}
+
+var letters = []rune("0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ")
+
+// Names implements the naming interface.
+func (n *randomNaming) Names(int) (string, string) {
+	b := make([]rune, 6)
+	for i := range b {
+		b[i] = letters[n.rand.Intn(len(letters))]
+	}
+	return branchPrefix + string(b), secretPrefix + string(b)
+}
+
+// names returns the names of the branch and the secret of a new pull request. The names set by
+// flags are used as is, and the others are generated by the naming strategy until they are not taken.
+func (i *InitActionCmd) names(ctx context.Context, repo *Repository, checkSecret bool) (string, string, error) {
+	if i.naming == nil {
+		i.naming = newRandomNaming(rand.NewSource(time.Now().UnixNano()))
+	}
+	for attempt := 0; attempt < maxNameAttempts; attempt++ {
+		branch, secret := i.naming.Names(attempt)
+		if i.Branch != "" {
+			branch = i.Branch
+		}
+		if i.SecretName != "" {
+			secret = i.SecretName
+		}
+		branchTaken, err := repo.BranchExists(ctx, branch)
+		if err != nil {
+			return "", "", err
+		}
+		if branchTaken && i.Branch != "" {
+			return "", "", fmt.Errorf("branch %q already exists, use a different --branch", branch)
+		}
+		var secretTaken bool
+		if checkSecret {
+			if secretTaken, err = repo.SecretExists(ctx, secret); err != nil {
+				return "", "", err
+			}
+			if secretTaken && i.SecretName != "" {
+				return "", "", fmt.Errorf("secret %q already exists, use a different --secret-name", secret)
+			}
+		}
+		if !branchTaken && !secretTaken {
+			return branch, secret, nil
+		}
+	}
+	return "", "", fmt.Errorf("failed to find a branch and a secret name that are not taken after %d attempts", maxNameAttempts)
+}
+
+// cloudTokenRe matches the secret holding the Atlas Cloud token in a generated workflow.
+var cloudTokenRe = regexp.MustCompile(`cloud-token:\s*\$\{\{\s*secrets\.([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+// existingNames returns the names of the branch and the secret of an existing pull request. Unless set
+// by flag, the secret is read from the workflow committed to the branch, as it may have been named by flag
+// too. Branches created by init-action fall back to the secret named after them.
+func (i *InitActionCmd) existingNames(ctx context.Context, repo *Repository, pr *github.PullRequest) (string, string, error) {
+	branch := pr.GetHead().GetRef()
+	if i.SecretName != "" || i.Offline {
+		return branch, i.SecretName, nil
+	}
+	content, err := repo.ReadBranchContent(ctx, branch, workflowPath)
+	if err == nil {
+		if m := cloudTokenRe.FindStringSubmatch(content); m != nil {
+			return branch, m[1], nil
+		}
+	}
+	if suffix, ok := strings.CutPrefix(branch, branchPrefix); ok {
+		return branch, secretPrefix + suffix, nil
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read the secret name from the workflow of %s, use --secret-name or --new-pr: %w", pr.GetHTMLURL(), err)
+	}
+	return "", "", fmt.Errorf("the workflow of %s does not read the Atlas Cloud token from a secret, use --secret-name or --new-pr", pr.GetHTMLURL())
+}
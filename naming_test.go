@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/cli/go-gh/pkg/repository"
+	"github.com/google/go-github/v49/github"
+	"github.com/stretchr/testify/require"
+)
+
+// seqNaming names the branch and the secret by the attempt number.
+type seqNaming struct{}
+
+func (seqNaming) Names(attempt int) (string, string) {
+	return fmt.Sprintf("atlas-ci-%d", attempt), fmt.Sprintf("ATLAS_CLOUD_TOKEN_%d", attempt)
+}
+
+func TestRandomNaming(t *testing.T) {
+	branch, secret := newRandomNaming(rand.NewSource(1)).Names(0)
+	require.Regexp(t, `^atlas-ci-[0-9A-Z]{6}$`, branch)
+	require.Equal(t, strings.TrimPrefix(branch, branchPrefix), strings.TrimPrefix(secret, secretPrefix))
+	// The names are reproducible with the same source.
+	b, s := newRandomNaming(rand.NewSource(1)).Names(0)
+	require.Equal(t, branch, b)
+	require.Equal(t, secret, s)
+	b, _ = newRandomNaming(rand.NewSource(2)).Names(0)
+	require.NotEqual(t, branch, b)
+}
+
+func TestInitActionCmd_names(t *testing.T) {
+	current, err := repository.Parse("owner/repo")
+	require.NoError(t, err)
+	newRepo := func(branches, secrets []string) *Repository {
+		client := createGHClient(&mockService{}, &mockService{branches: branches})
+		client.Actions = &mockService{secrets: secrets}
+		return NewRepository(client, current, "master")
+	}
+	for _, tt := range []struct {
+		name               string
+		cmd                *InitActionCmd
+		branches, secrets  []string
+		checkSecret        bool
+		wantBranch, wantSN string
+		wantErr            string
+	}{
+		{
+			name:       "first attempt",
+			cmd:        &InitActionCmd{},
+			wantBranch: "atlas-ci-0",
+			wantSN:     "ATLAS_CLOUD_TOKEN_0",
+		},
+		{
+			name:        "branch and secret taken",
+			cmd:         &InitActionCmd{},
+			branches:    []string{"atlas-ci-0"},
+			secrets:     []string{"ATLAS_CLOUD_TOKEN_1"},
+			checkSecret: true,
+			wantBranch:  "atlas-ci-2",
+			wantSN:      "ATLAS_CLOUD_TOKEN_2",
+		},
+		{
+			name:       "secrets are not checked",
+			cmd:        &InitActionCmd{},
+			secrets:    []string{"ATLAS_CLOUD_TOKEN_0"},
+			wantBranch: "atlas-ci-0",
+			wantSN:     "ATLAS_CLOUD_TOKEN_0",
+		},
+		{
+			name:        "flags",
+			cmd:         &InitActionCmd{Branch: "ci/atlas", SecretName: "ATLAS_TOKEN"},
+			branches:    []string{"atlas-ci-0"},
+			checkSecret: true,
+			wantBranch:  "ci/atlas",
+			wantSN:      "ATLAS_TOKEN",
+		},
+		{
+			name:        "branch flag and generated secret",
+			cmd:         &InitActionCmd{Branch: "ci/atlas"},
+			secrets:     []string{"ATLAS_CLOUD_TOKEN_0"},
+			checkSecret: true,
+			wantBranch:  "ci/atlas",
+			wantSN:      "ATLAS_CLOUD_TOKEN_1",
+		},
+		{
+			name:     "branch flag taken",
+			cmd:      &InitActionCmd{Branch: "ci/atlas"},
+			branches: []string{"ci/atlas"},
+			wantErr:  `branch "ci/atlas" already exists, use a different --branch`,
+		},
+		{
+			name:        "secret flag taken",
+			cmd:         &InitActionCmd{SecretName: "ATLAS_TOKEN"},
+			secrets:     []string{"ATLAS_TOKEN"},
+			checkSecret: true,
+			wantErr:     `secret "ATLAS_TOKEN" already exists, use a different --secret-name`,
+		},
+		{
+			name:     "all taken",
+			cmd:      &InitActionCmd{},
+			branches: []string{"atlas-ci-0", "atlas-ci-1", "atlas-ci-2", "atlas-ci-3", "atlas-ci-4"},
+			wantErr:  "failed to find a branch and a secret name that are not taken after 5 attempts",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cmd.naming = seqNaming{}
+			branch, secret, err := tt.cmd.names(context.Background(), newRepo(tt.branches, tt.secrets), tt.checkSecret)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.wantBranch, branch)
+			require.Equal(t, tt.wantSN, secret)
+		})
+	}
+}
+
+func TestRunInitActionCmd_Naming(t *testing.T) {
+	current, err := repository.Parse("owner/repo")
+	require.NoError(t, err)
+	run := func(cmd *InitActionCmd) (*github.NewPullRequest, error) {
+		var out strings.Builder
+		client := createGHClient(&mockService{getContentError: &github.ErrorResponse{Message: "Not Found"}}, &mockService{branches: []string{"atlas-ci-0"}})
+		cmd.Offline, cmd.DirPath, cmd.driver, cmd.SchemaScope = true, "migrations", "MYSQL", true
+		cmd.stdin, cmd.stdout = &stdinBuffer{strings.NewReader("")}, &out
+		cmd.naming = seqNaming{}
+		cmd.JSON = true
+		if err := cmd.Run(context.Background(), client, current); err != nil {
+			return nil, err
+		}
+		var res struct{ Branch string }
+		require.NoError(t, json.Unmarshal([]byte(out.String()), &res))
+		created := client.PullRequests.(*mockPullRequestsService).created
+		require.Equal(t, res.Branch, created.GetHead())
+		return created, nil
+	}
+	pr, err := run(&InitActionCmd{})
+	require.NoError(t, err)
+	require.Equal(t, "atlas-ci-1", pr.GetHead())
+
+	pr, err = run(&InitActionCmd{Branch: "ci/atlas"})
+	require.NoError(t, err)
+	require.Equal(t, "ci/atlas", pr.GetHead())
+
+	_, err = run(&InitActionCmd{Branch: "atlas-ci-0"})
+	require.EqualError(t, err, `branch "atlas-ci-0" already exists, use a different --branch`)
+
+	_, err = run(&InitActionCmd{Branch: "ci atlas"})
+	require.EqualError(t, err, `invalid branch name "ci atlas"`)
+
+	_, err = run(&InitActionCmd{SecretName: "GITHUB_TOKEN"})
+	require.EqualError(t, err, `invalid secret name "GITHUB_TOKEN", expected alphanumeric characters or underscores, not starting with a number or GITHUB_`)
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+
+	"github.com/cli/go-gh/pkg/jq"
+	"github.com/cli/go-gh/pkg/template"
+)
+
+// outputFlags are the flags for printing the result of a command in a machine-readable format.
+type outputFlags struct {
+	JSON     bool   `name:"json" help:"Output in JSON format."`
+	JQ       string `short:"q" name:"jq" placeholder:"EXPRESSION" xor:"format" help:"Filter JSON output using a jq expression."`
+	Template string `short:"T" placeholder:"STRING" xor:"format" help:"Format JSON output using a Go template."`
+}
+
+// enabled reports whether the output is machine-readable. The --jq and --template flags imply --json.
+func (o *outputFlags) enabled() bool {
+	return o.JSON || o.JQ != "" || o.Template != ""
+}
+
+// print writes v to w as JSON, filtered by the jq expression or formatted by the template, if set.
+func (o *outputFlags) print(w io.Writer, v any) error {
+	switch {
+	case o.JQ != "":
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		return jq.Evaluate(bytes.NewReader(b), w, o.JQ)
+	case o.Template != "":
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		t := template.New(w, 80, false)
+		if err := t.Parse(o.Template); err != nil {
+			return err
+		}
+		if err := t.Execute(bytes.NewReader(b)); err != nil {
+			return err
+		}
+		return t.Flush()
+	default:
+		return printJSON(w, v)
+	}
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/1lann/promptui"
+	"github.com/cli/go-gh/pkg/repository"
+
+	"ariga.io/gh-atlas/cloudapi"
+)
+
+type (
+	// PlanCmd groups the commands for reviewing declarative schema plans.
+	PlanCmd struct {
+		cloudFlags
+		Repo    string         `short:"R" help:"GitHub repository <owner>/<name>, defaults to the current repository."`
+		List    PlanListCmd    `cmd:"" help:"List the schema plans of the repository."`
+		Show    PlanShowCmd    `cmd:"" help:"Show a schema plan."`
+		Approve PlanApproveCmd `cmd:"" help:"Approve a schema plan."`
+		Reject  PlanRejectCmd  `cmd:"" help:"Reject a schema plan."`
+	}
+	// PlanListCmd is the command for listing schema plans.
+	PlanListCmd struct {
+		Status cloudapi.PlanStatus `optional:"" help:"Filter by plan status (PENDING, APPROVED, REJECTED)."`
+		PR     int                 `optional:"" name:"pr" help:"Filter by pull request number."`
+		outputFlags
+	}
+	// PlanShowCmd is the command for showing a schema plan.
+	PlanShowCmd struct {
+		Plan string `arg:"" help:"Pull request number or name of the plan."`
+		outputFlags
+	}
+	// PlanApproveCmd is the command for approving a schema plan.
+	PlanApproveCmd struct {
+		Plan string `arg:"" help:"Pull request number or name of the plan."`
+		outputFlags
+	}
+	// PlanRejectCmd is the command for rejecting a schema plan.
+	PlanRejectCmd struct {
+		Plan string `arg:"" help:"Pull request number or name of the plan."`
+		outputFlags
+	}
+)
+
+func (p *PlanCmd) Help() string {
+	return `Plans are created by the schema/plan action on pull requests, and applied
+on merge only once approved.
+
+Examples:
+	gh atlas plan list --status=PENDING
+	gh atlas plan show 42
+	gh atlas plan list --jq '.[] | select(.pullRequest == 42) | .url'
+	gh atlas plan approve 42
+	gh atlas plan reject add-users-table`
+}
+
+// repository returns the GitHub repository of the plans in the <owner>/<name> format.
+func (p *PlanCmd) repository(current repository.Repository) (string, error) {
+	if p.Repo != "" {
+		r, err := repository.Parse(p.Repo)
+		if err != nil {
+			return "", err
+		}
+		current = r
+	}
+	return current.Owner() + "/" + current.Name(), nil
+}
+
+// plan finds the plan by its name, or by the pull request number.
+func (p *PlanCmd) plan(ctx context.Context, current repository.Repository, key string) (*cloudapi.Plan, cloudapi.API, error) {
+	api, err := p.client()
+	if err != nil {
+		return nil, nil, err
+	}
+	input := cloudapi.PlansInput{Name: key}
+	if n, err := strconv.Atoi(strings.TrimPrefix(key, "#")); err == nil {
+		repo, err := p.repository(current)
+		if err != nil {
+			return nil, nil, err
+		}
+		input = cloudapi.PlansInput{Repo: repo, PullRequest: n}
+	}
+	plans, err := api.Plans(ctx, input)
+	switch {
+	case err != nil:
+		return nil, nil, err
+	case len(plans) == 0:
+		return nil, nil, fmt.Errorf("no schema plan found for %q", key)
+	case len(plans) > 1:
+		names := make([]string, len(plans))
+		for i := range plans {
+			names[i] = plans[i].Name
+		}
+		return nil, nil, fmt.Errorf("multiple schema plans found for %q, use one of the plan names: %s", key, strings.Join(names, ", "))
+	}
+	return &plans[0], api, nil
+}
+
+// Run the plan list command.
+func (l *PlanListCmd) Run(ctx context.Context, p *PlanCmd, current repository.Repository) error {
+	api, err := p.client()
+	if err != nil {
+		return err
+	}
+	repo, err := p.repository(current)
+	if err != nil {
+		return err
+	}
+	plans, err := api.Plans(ctx, cloudapi.PlansInput{Repo: repo, PullRequest: l.PR, Status: l.Status})
+	if err != nil {
+		return err
+	}
+	if l.enabled() {
+		return l.print(p.out(), plans)
+	}
+	w := tabwriter.NewWriter(p.out(), 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tSTATUS\tSCHEMA\tPR")
+	for _, plan := range plans {
+		fmt.Fprintf(w, "%s\t%s\t%s\t#%d\n", plan.Name, plan.Status, plan.Schema, plan.PullRequest)
+	}
+	return w.Flush()
+}
+
+// Run the plan show command.
+func (s *PlanShowCmd) Run(ctx context.Context, p *PlanCmd, current repository.Repository) error {
+	plan, _, err := p.plan(ctx, current, s.Plan)
+	if err != nil {
+		return err
+	}
+	if s.enabled() {
+		return s.print(p.out(), plan)
+	}
+	fmt.Fprintf(p.out(), "Name:         %s\n", plan.Name)
+	fmt.Fprintf(p.out(), "Status:       %s\n", plan.Status)
+	fmt.Fprintf(p.out(), "Schema:       %s\n", plan.Schema)
+	fmt.Fprintf(p.out(), "Pull request: #%d\n", plan.PullRequest)
+	fmt.Fprintf(p.out(), "URL:          %s\n", plan.URL)
+	fmt.Fprintf(p.out(), "\n%s\n", strings.TrimSpace(plan.Migration))
+	return nil
+}
+
+// Run the plan approve command.
+func (a *PlanApproveCmd) Run(ctx context.Context, p *PlanCmd, current repository.Repository) error {
+	plan, api, err := p.plan(ctx, current, a.Plan)
+	if err != nil {
+		return err
+	}
+	if plan.Status == cloudapi.PlanApproved {
+		return fmt.Errorf("schema plan %q is already approved", plan.Name)
+	}
+	if plan, err = api.ApprovePlan(ctx, plan.Name); err != nil {
+		return err
+	}
+	if a.enabled() {
+		return a.print(p.out(), plan)
+	}
+	fmt.Fprintf(p.out(), "%s %s %s\n",
+		promptui.IconGood,
+		promptui.Styler(promptui.FGFaint)("Approved schema plan:"),
+		plan.Name,
+	)
+	return nil
+}
+
+// Run the plan reject command.
+func (r *PlanRejectCmd) Run(ctx context.Context, p *PlanCmd, current repository.Repository) error {
+	plan, api, err := p.plan(ctx, current, r.Plan)
+	if err != nil {
+		return err
+	}
+	if plan.Status == cloudapi.PlanRejected {
+		return fmt.Errorf("schema plan %q is already rejected", plan.Name)
+	}
+	if plan, err = api.RejectPlan(ctx, plan.Name); err != nil {
+		return err
+	}
+	if r.enabled() {
+		return r.print(p.out(), plan)
+	}
+	fmt.Fprintf(p.out(), "%s %s %s\n",
+		promptui.IconGood,
+		promptui.Styler(promptui.FGFaint)("Rejected schema plan:"),
+		plan.Name,
+	)
+	return nil
+}
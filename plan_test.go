@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"ariga.io/gh-atlas/cloudapi"
+	"github.com/cli/go-gh/pkg/repository"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanCmd(t *testing.T) {
+	repo, err := repository.Parse("owner/repo")
+	require.NoError(t, err)
+	api := &mockCloudAPI{
+		plans: []cloudapi.Plan{
+			{Name: "add-users", Status: cloudapi.PlanPending, Schema: "app", PullRequest: 1, URL: "https://ariga.atlasgo.cloud/plans/1", Migration: "CREATE TABLE `users` (`id` int NOT NULL);\n"},
+			{Name: "add-posts", Status: cloudapi.PlanApproved, Schema: "app", PullRequest: 2},
+			{Name: "add-tags", Status: cloudapi.PlanPending, Schema: "app", PullRequest: 3},
+			{Name: "add-tags-index", Status: cloudapi.PlanPending, Schema: "tags", PullRequest: 3},
+		},
+	}
+	var out bytes.Buffer
+	cmd := &PlanCmd{cloudFlags: cloudFlags{api: api, stdout: &out}}
+
+	require.NoError(t, (&PlanListCmd{Status: cloudapi.PlanPending}).Run(context.Background(), cmd, repo))
+	require.Equal(t, `NAME            STATUS   SCHEMA  PR
+add-users       PENDING  app     #1
+add-tags        PENDING  app     #3
+add-tags-index  PENDING  tags    #3
+`, out.String())
+
+	out.Reset()
+	require.NoError(t, (&PlanListCmd{PR: 3, outputFlags: outputFlags{JQ: ".[].name"}}).Run(context.Background(), cmd, repo))
+	require.Equal(t, "add-tags\nadd-tags-index\n", out.String())
+
+	out.Reset()
+	require.NoError(t, (&PlanShowCmd{Plan: "1", outputFlags: outputFlags{Template: "{{.name}} {{.status}}"}}).Run(context.Background(), cmd, repo))
+	require.Equal(t, "add-users PENDING", out.String())
+
+	out.Reset()
+	require.NoError(t, (&PlanShowCmd{Plan: "1"}).Run(context.Background(), cmd, repo))
+	require.Equal(t, "Name:         add-users\nStatus:       PENDING\nSchema:       app\nPull request: #1\nURL:          https://ariga.atlasgo.cloud/plans/1\n\nCREATE TABLE `users` (`id` int NOT NULL);\n", out.String())
+
+	out.Reset()
+	require.NoError(t, (&PlanApproveCmd{Plan: "#1"}).Run(context.Background(), cmd, repo))
+	require.Contains(t, out.String(), "Approved schema plan:")
+	require.Contains(t, out.String(), "add-users")
+	require.Equal(t, cloudapi.PlanApproved, api.plans[0].Status)
+
+	err = (&PlanApproveCmd{Plan: "add-posts"}).Run(context.Background(), cmd, repo)
+	require.EqualError(t, err, `schema plan "add-posts" is already approved`)
+
+	err = (&PlanRejectCmd{Plan: "3"}).Run(context.Background(), cmd, repo)
+	require.EqualError(t, err, `multiple schema plans found for "3", use one of the plan names: add-tags, add-tags-index`)
+
+	out.Reset()
+	require.NoError(t, (&PlanRejectCmd{Plan: "add-tags"}).Run(context.Background(), cmd, repo))
+	require.Contains(t, out.String(), "Rejected schema plan:")
+	require.Contains(t, out.String(), "add-tags")
+	require.Equal(t, cloudapi.PlanRejected, api.plans[2].Status)
+
+	err = (&PlanShowCmd{Plan: "unknown"}).Run(context.Background(), cmd, repo)
+	require.EqualError(t, err, `no schema plan found for "unknown"`)
+}
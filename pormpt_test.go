@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"os"
+	"slices"
+	"strconv"
 	"testing"
 
 	"ariga.io/gh-atlas/cloudapi"
@@ -134,6 +136,37 @@ func TestRunInitActionCmd_setConfigPath(t *testing.T) {
 					HasURL:       true,
 					HasSchemaSrc: true,
 					HasRepoName:  true,
+					SchemaSrc:    []string{"file://./schema.sql"},
+				},
+			},
+		},
+		{
+			name: "schema src list and variable",
+			re: &mockRepoExplorer{
+				content: `
+				variable "src" {
+					type = string
+				}
+				env "list" {
+					schema {
+						src = ["file://schema", "file://base.sql"]
+					}
+				}
+				env "var" {
+					schema {
+						src = var.src
+					}
+				}
+		`,
+			},
+			configs: []string{"atlas.hcl"},
+			// enter, arrow key down, enter
+			prompt: "\n\x1b[B\n",
+			expected: &InitActionCmd{
+				env: gen.Env{
+					Name:         "var",
+					Path:         "atlas.hcl",
+					HasSchemaSrc: true,
 				},
 			},
 		},
@@ -163,6 +196,8 @@ func TestRunInitActionCmd_selectRepo(t *testing.T) {
 	tests := []struct {
 		name         string
 		repos        []cloudapi.Repo
+		pageSize     int
+		cmd          *InitActionCmd
 		prompt       string
 		expectedRepo *cloudapi.Repo
 		expectedErr  error
@@ -192,11 +227,85 @@ func TestRunInitActionCmd_selectRepo(t *testing.T) {
 			prompt:       "\x1b[B\n",
 			expectedRepo: &cloudapi.Repo{Title: "Repo2", URL: "url2", Type: cloudapi.DirectoryType, Driver: "POSTGRESQL"},
 		},
+		{
+			name: "Multiple pages, load more and select third",
+			repos: []cloudapi.Repo{
+				{Title: "Repo1", URL: "url1", Type: cloudapi.SchemaType, Driver: "MYSQL"},
+				{Title: "Repo2", URL: "url2", Type: cloudapi.DirectoryType, Driver: "POSTGRESQL"},
+				{Title: "Repo3", URL: "url3", Type: cloudapi.DirectoryType, Driver: "SQLITE"},
+			},
+			pageSize: 2,
+			// arrow key down twice to "load more", enter, enter
+			prompt:       "\x1b[B\x1b[B\n\n",
+			expectedRepo: &cloudapi.Repo{Title: "Repo3", URL: "url3", Type: cloudapi.DirectoryType, Driver: "SQLITE"},
+		},
+		{
+			name: "Single repo in first page with more pages",
+			repos: []cloudapi.Repo{
+				{Title: "Repo1", URL: "url1", Type: cloudapi.SchemaType, Driver: "MYSQL"},
+				{Title: "Repo2", URL: "url2", Type: cloudapi.DirectoryType, Driver: "POSTGRESQL"},
+			},
+			pageSize: 1,
+			// arrow key down to "load more", enter, enter
+			prompt:       "\x1b[B\n\n",
+			expectedRepo: &cloudapi.Repo{Title: "Repo2", URL: "url2", Type: cloudapi.DirectoryType, Driver: "POSTGRESQL"},
+		},
+		{
+			name: "Dir name found in later page",
+			repos: []cloudapi.Repo{
+				{Title: "Repo1", Slug: "slug1", Type: cloudapi.DirectoryType, Driver: "MYSQL"},
+				{Title: "Repo2", Slug: "slug2", Type: cloudapi.DirectoryType, Driver: "POSTGRESQL"},
+			},
+			pageSize:     1,
+			cmd:          &InitActionCmd{DirName: "slug2"},
+			expectedRepo: &cloudapi.Repo{Title: "Repo2", Slug: "slug2", Type: cloudapi.DirectoryType, Driver: "POSTGRESQL"},
+		},
+		{
+			name: "Dir name not found in any page",
+			repos: []cloudapi.Repo{
+				{Title: "Repo1", Slug: "slug1", Type: cloudapi.DirectoryType, Driver: "MYSQL"},
+				{Title: "Repo2", Slug: "slug2", Type: cloudapi.DirectoryType, Driver: "POSTGRESQL"},
+			},
+			pageSize:    1,
+			cmd:         &InitActionCmd{DirName: "slug3"},
+			expectedErr: errors.New("no repository with given name found"),
+		},
 		{
 			name:        "No repos",
 			repos:       []cloudapi.Repo{},
 			expectedErr: errors.New("no repositories found"),
 		},
+		{
+			name:        "No repos, decline creating one",
+			repos:       []cloudapi.Repo{},
+			prompt:      "n\n",
+			expectedErr: errors.New("no repositories found"),
+		},
+		{
+			name:  "No repos, create schema repo",
+			repos: []cloudapi.Repo{},
+			// confirm, arrow key down to "schema", enter, slug, enter, enter (first driver)
+			prompt:       "y\n\x1b[B\napp\n\n",
+			expectedRepo: &cloudapi.Repo{Title: "app", Slug: "app", URL: "atlas://app", Type: cloudapi.SchemaType, Driver: "MYSQL"},
+		},
+		{
+			name: "Dir name not found, create directory repo",
+			repos: []cloudapi.Repo{
+				{Title: "Repo1", Slug: "slug1", Type: cloudapi.DirectoryType, Driver: "MYSQL"},
+			},
+			cmd:          &InitActionCmd{DirName: "app", driver: "POSTGRESQL"},
+			prompt:       "y\n",
+			expectedRepo: &cloudapi.Repo{Title: "app", Slug: "app", URL: "atlas://app", Type: cloudapi.DirectoryType, Driver: "POSTGRESQL"},
+		},
+		{
+			name: "URL not found, create schema repo",
+			repos: []cloudapi.Repo{
+				{Title: "Repo1", Slug: "slug1", URL: "atlas://slug1", Type: cloudapi.SchemaType, Driver: "MYSQL"},
+			},
+			cmd:          &InitActionCmd{To: "atlas://app:v1", driver: "SQLITE"},
+			prompt:       "y\n",
+			expectedRepo: &cloudapi.Repo{Title: "app", Slug: "app", URL: "atlas://app", Type: cloudapi.SchemaType, Driver: "SQLITE"},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -206,11 +315,12 @@ func TestRunInitActionCmd_selectRepo(t *testing.T) {
 			require.NoError(t, err)
 			err = w.Close()
 			require.NoError(t, err)
-			mockAPI := new(mockCloudAPI)
-			mockAPI.repos = tt.repos
-			cmd := &InitActionCmd{
-				stdin: &stdinBuffer{r},
+			mockAPI := &mockCloudAPI{repos: tt.repos, pageSize: tt.pageSize}
+			cmd := tt.cmd
+			if cmd == nil {
+				cmd = &InitActionCmd{}
 			}
+			cmd.stdin = &stdinBuffer{r}
 			repo, err := cmd.selectAtlasRepo(context.Background(), mockAPI)
 			if tt.expectedErr != nil {
 				require.Error(t, err)
@@ -224,6 +334,55 @@ func TestRunInitActionCmd_selectRepo(t *testing.T) {
 	}
 }
 
+func TestRunInitActionCmd_checkTokenPermissions(t *testing.T) {
+	dir := &cloudapi.Repo{Slug: "dir", Type: cloudapi.DirectoryType}
+	schema := &cloudapi.Repo{Slug: "schema", Type: cloudapi.SchemaType}
+	tests := []struct {
+		name    string
+		api     *mockCloudAPI
+		repo    *cloudapi.Repo
+		wantErr string
+	}{
+		{
+			name: "bot token with migrate push",
+			api:  &mockCloudAPI{tokenInfo: &cloudapi.TokenInfo{Type: cloudapi.BotToken, Scopes: []string{cloudapi.ScopeMigratePush}}},
+			repo: dir,
+		},
+		{
+			name:    "bot token without schema push",
+			api:     &mockCloudAPI{tokenInfo: &cloudapi.TokenInfo{Type: cloudapi.BotToken, Scopes: []string{cloudapi.ScopeMigratePush}}},
+			repo:    schema,
+			wantErr: `the given Atlas token is not allowed to schema push to repository "schema", please use a bot token with the required permissions`,
+		},
+		{
+			name:    "token limited to other repos",
+			api:     &mockCloudAPI{tokenInfo: &cloudapi.TokenInfo{Type: cloudapi.BotToken, Scopes: []string{cloudapi.ScopeMigratePush}, Repos: []string{"other"}}},
+			repo:    dir,
+			wantErr: `the given Atlas token is not allowed to migrate push to repository "dir", please use a bot token with the required permissions`,
+		},
+		{
+			name: "personal token",
+			api:  &mockCloudAPI{tokenInfo: &cloudapi.TokenInfo{Type: cloudapi.PersonalToken, Name: "a8m", Scopes: []string{cloudapi.ScopeSchemaPush}, Repos: []string{"schema"}}},
+			repo: schema,
+		},
+		{
+			name: "permissions cannot be verified",
+			api:  &mockCloudAPI{tokenErr: errors.New("unknown field tokenInfo")},
+			repo: schema,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := (&InitActionCmd{}).checkTokenPermissions(context.Background(), tt.api, tt.repo)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
 func TestRunInitActionCmd_ChooseDirPath(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -290,13 +449,123 @@ func (m *mockRepoExplorer) ConfigFiles(ctx context.Context) ([]string, error) {
 }
 
 type mockCloudAPI struct {
-	repos []cloudapi.Repo
+	plans     []cloudapi.Plan
+	reports   map[string][]cloudapi.Report // by commit
+	repos     []cloudapi.Repo
+	pageSize  int // zero means all repos are returned in a single page
+	tokenInfo *cloudapi.TokenInfo
+	tokenErr  error
 }
 
-func (m *mockCloudAPI) Repos(ctx context.Context) ([]cloudapi.Repo, error) {
-	return m.repos, nil
+func (m *mockCloudAPI) Repos(_ context.Context, input cloudapi.ReposInput) (*cloudapi.ReposPage, error) {
+	if m.pageSize == 0 {
+		return &cloudapi.ReposPage{Repos: m.repos}, nil
+	}
+	start, _ := strconv.Atoi(input.After)
+	end := min(start+m.pageSize, len(m.repos))
+	return &cloudapi.ReposPage{
+		Repos: m.repos[start:end],
+		PageInfo: cloudapi.PageInfo{
+			HasNextPage: end < len(m.repos),
+			EndCursor:   strconv.Itoa(end),
+		},
+	}, nil
+}
+
+func (m *mockCloudAPI) CreateRepo(_ context.Context, input cloudapi.CreateRepoInput) (*cloudapi.Repo, error) {
+	repo := cloudapi.Repo{
+		Title:  input.Slug,
+		Slug:   input.Slug,
+		URL:    "atlas://" + input.Slug,
+		Type:   input.Type,
+		Driver: input.Driver,
+	}
+	m.repos = append(m.repos, repo)
+	return &repo, nil
+}
+
+func (m *mockCloudAPI) Repo(_ context.Context, slug string) (*cloudapi.Repo, error) {
+	idx := slices.IndexFunc(m.repos, func(r cloudapi.Repo) bool {
+		return r.Slug == slug
+	})
+	if idx == -1 {
+		return nil, cloudapi.ErrNotFound
+	}
+	return &m.repos[idx], nil
+}
+
+func (m *mockCloudAPI) Reports(_ context.Context, input cloudapi.ReportsInput) ([]cloudapi.Report, error) {
+	return m.reports[input.Commit], nil
+}
+
+func (m *mockCloudAPI) Plans(_ context.Context, input cloudapi.PlansInput) ([]cloudapi.Plan, error) {
+	var plans []cloudapi.Plan
+	for _, p := range m.plans {
+		if (input.Name == "" || p.Name == input.Name) &&
+			(input.PullRequest == 0 || p.PullRequest == input.PullRequest) &&
+			(input.Status == "" || p.Status == input.Status) {
+			plans = append(plans, p)
+		}
+	}
+	return plans, nil
+}
+
+func (m *mockCloudAPI) ApprovePlan(_ context.Context, name string) (*cloudapi.Plan, error) {
+	return m.setPlanStatus(name, cloudapi.PlanApproved)
+}
+
+func (m *mockCloudAPI) RejectPlan(_ context.Context, name string) (*cloudapi.Plan, error) {
+	return m.setPlanStatus(name, cloudapi.PlanRejected)
+}
+
+func (m *mockCloudAPI) setPlanStatus(name string, status cloudapi.PlanStatus) (*cloudapi.Plan, error) {
+	for i := range m.plans {
+		if m.plans[i].Name == name {
+			m.plans[i].Status = status
+			return &m.plans[i], nil
+		}
+	}
+	return nil, cloudapi.ErrNotFound
+}
+
+func (m *mockCloudAPI) TokenInfo(context.Context) (*cloudapi.TokenInfo, error) {
+	return m.tokenInfo, m.tokenErr
 }
 
 func (m *mockCloudAPI) ValidateToken(ctx context.Context) error {
 	return nil
 }
+
+func TestParseInput(t *testing.T) {
+	in, err := parseInput("reason")
+	require.NoError(t, err)
+	require.Equal(t, gen.Input{Name: "reason", Required: true}, in)
+	in, err = parseInput("dry-run:boolean=true")
+	require.NoError(t, err)
+	require.Equal(t, gen.Input{Name: "dry-run", Type: "boolean", Default: "true"}, in)
+	in, err = parseInput("target=prod=eu")
+	require.NoError(t, err)
+	require.Equal(t, gen.Input{Name: "target", Default: "prod=eu"}, in)
+	_, err = parseInput(":string")
+	require.EqualError(t, err, `invalid dispatch input ":string", expected NAME[:TYPE][=DEFAULT]`)
+	_, err = parseInput("level:choice")
+	require.EqualError(t, err, `unsupported type "choice" of dispatch input "level", expected string, boolean, number or environment`)
+	_, err = parseInput("dry run")
+	require.EqualError(t, err, `invalid dispatch input name "dry run", expected letters, digits, '-' and '_'`)
+}
+
+func TestInitActionCmd_triggers(t *testing.T) {
+	cmd := &InitActionCmd{DispatchInputs: []string{"dry-run:boolean=true", "reason"}}
+	tr, err := cmd.triggers()
+	require.NoError(t, err)
+	require.Len(t, tr.Inputs, 2)
+	require.Equal(t, "DRY_RUN", tr.Inputs[0].Env())
+
+	cmd.DispatchInputs = []string{"dry-run", "dry_run"}
+	_, err = cmd.triggers()
+	require.EqualError(t, err, `dispatch inputs "dry-run" and "dry_run" are both exposed as the DRY_RUN environment variable`)
+
+	cmd.DispatchInputs = []string{"github_ref"}
+	_, err = cmd.triggers()
+	require.EqualError(t, err, `dispatch input "github_ref" cannot start with GITHUB_, it is exposed as the GITHUB_REF environment variable`)
+}
@@ -4,8 +4,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"regexp"
 	"slices"
 	"strings"
+	"text/template"
 
 	"ariga.io/gh-atlas/cloudapi"
 	"ariga.io/gh-atlas/gen"
@@ -13,6 +18,7 @@ import (
 	"github.com/hashicorp/hcl/v2/hclparse"
 	"github.com/hashicorp/hcl/v2/hclsyntax"
 	"github.com/pkg/browser"
+	"github.com/zclconf/go-cty/cty"
 )
 
 type flowType string
@@ -28,8 +34,14 @@ func (i *InitActionCmd) setParams(ctx context.Context, re RepoExplorer, cloud cl
 		err  error
 		repo *cloudapi.Repo
 	)
-	if repo, err = i.selectAtlasRepo(ctx, cloud); err != nil {
-		return err
+	if !i.Offline {
+		if repo, err = i.selectAtlasRepo(ctx, cloud); err != nil {
+			return err
+		}
+		if err = i.checkTokenPermissions(ctx, cloud, repo); err != nil {
+			return err
+		}
+		i.atlasRepo = repo
 	}
 	if err = i.initializeFlow(repo); err != nil {
 		return err
@@ -48,10 +60,12 @@ func (i *InitActionCmd) setParams(ctx context.Context, re RepoExplorer, cloud cl
 		if i.DirName == "" && repo != nil {
 			i.DirName = repo.Slug
 		}
-		fmt.Printf("%s %s %s\n",
-			promptui.IconGood,
-			promptui.Styler(promptui.FGFaint)("Target migrations directory name:"),
-			i.DirName)
+		if !i.Offline {
+			fmt.Fprintf(i.out(), "%s %s %s\n",
+				promptui.IconGood,
+				promptui.Styler(promptui.FGFaint)("Target migrations directory name:"),
+				i.DirName)
+		}
 		dirs, err := re.MigrationDirectories(ctx)
 		if err != nil {
 			return err
@@ -80,7 +94,8 @@ func (i *InitActionCmd) setParams(ctx context.Context, re RepoExplorer, cloud cl
 	if repo != nil {
 		i.driver = repo.Driver
 	}
-	if !i.env.HasDevURL && i.driver == "" {
+	// The driver selects the dev-database, unless its URL is given by the config or a secret.
+	if !i.env.HasDevURL && i.DevDB != gen.DevDBCustom && i.driver == "" {
 		if err := i.setDriver(); err != nil {
 			return err
 		}
@@ -100,7 +115,7 @@ func (i *InitActionCmd) initializeFlow(repo *cloudapi.Repo) error {
 	switch {
 	case repoType == cloudapi.SchemaType || i.To != "":
 		i.flow = Declarative
-	case repoType == cloudapi.DirectoryType || i.DirName != "":
+	case repoType == cloudapi.DirectoryType || i.DirName != "" || i.Offline:
 		i.flow = Versioned
 	default:
 		return fmt.Errorf("cannot infer flow from repo type %q", repoType)
@@ -114,6 +129,7 @@ func (i *InitActionCmd) setDriver() error {
 		HideHelp: true,
 		Items:    gen.Drivers,
 		Stdin:    i.stdin,
+		Stdout:   i.promptOut(),
 		Templates: &promptui.SelectTemplates{
 			Selected: fmt.Sprintf(`{{ "%s" | green }} {{ "Driver:" | faint }} {{ . }}`, promptui.IconGood),
 		},
@@ -123,47 +139,218 @@ func (i *InitActionCmd) setDriver() error {
 	return err
 }
 
+// reposPageSize is the number of repositories fetched from Atlas Cloud per request.
+const reposPageSize = 50
+
+// loadMoreRepos is the item appended to the repositories list when more pages are available.
+const loadMoreRepos = "load more repositories"
+
 func (i *InitActionCmd) selectAtlasRepo(ctx context.Context, cloud cloudapi.API) (*cloudapi.Repo, error) {
-	var choose int
-	repos, err := cloud.Repos(ctx)
 	switch {
-	case err != nil:
-		return nil, err
-	case len(repos) == 0:
-		return nil, errors.New("no repositories found")
 	case i.To != "": // Search by URL
-		choose = slices.IndexFunc(repos, func(r cloudapi.Repo) bool {
+		repo, err := findRepo(ctx, cloud, &cloudapi.ReposFilter{Type: cloudapi.SchemaType}, func(r cloudapi.Repo) bool {
 			return r.URL == i.To
 		})
-		if choose == -1 {
-			return nil, errors.New("no repository with given URL found")
+		if err == nil && repo == nil {
+			return i.createAtlasRepo(ctx, cloud, errors.New("no repository with given URL found"))
 		}
+		return repo, err
 	case i.DirName != "": // Search by directory name
-		choose = slices.IndexFunc(repos, func(r cloudapi.Repo) bool {
+		repo, err := findRepo(ctx, cloud, &cloudapi.ReposFilter{Type: cloudapi.DirectoryType, Search: i.DirName}, func(r cloudapi.Repo) bool {
 			return r.Slug == i.DirName
 		})
-		if choose == -1 {
-			return nil, errors.New("no repository with given name found")
+		if err == nil && repo == nil {
+			return i.createAtlasRepo(ctx, cloud, errors.New("no repository with given name found"))
+		}
+		return repo, err
+	}
+	page, err := cloud.Repos(ctx, cloudapi.ReposInput{First: reposPageSize})
+	switch {
+	case err != nil:
+		return nil, err
+	case len(page.Repos) == 0:
+		return i.createAtlasRepo(ctx, cloud, errors.New("no repositories found"))
+	case len(page.Repos) == 1 && !page.PageInfo.HasNextPage:
+		return &page.Repos[0], nil
+	}
+	var (
+		choose int
+		repos  = page.Repos
+	)
+	for {
+		items := make([]cloudapi.Repo, len(repos), len(repos)+1)
+		copy(items, repos)
+		if page.PageInfo.HasNextPage {
+			items = append(items, cloudapi.Repo{Title: loadMoreRepos})
 		}
-	case len(repos) == 1:
-		choose = 0
-	default:
 		prompt := promptui.Select{
-			Label: "Select an Atlas Cloud Repository",
-			Items: repos,
-			Stdin: i.stdin,
+			Label:  "Select an Atlas Cloud Repository",
+			Items:  items,
+			Stdin:  i.stdin,
+			Stdout: i.promptOut(),
+			Searcher: func(input string, idx int) bool {
+				// Keep the "load more" item visible while searching.
+				if idx == len(repos) {
+					return true
+				}
+				input = strings.ToLower(input)
+				return strings.Contains(strings.ToLower(items[idx].Title), input) ||
+					strings.Contains(strings.ToLower(items[idx].Slug), input)
+			},
 			Templates: &promptui.SelectTemplates{
 				Active:   `▸ {{ .Title }}`,
 				Inactive: `  {{ .Title }}`,
-				Selected: fmt.Sprintf(`{{ "%s" | green }} {{ "Selected repository:" | faint }} {{ .Title }}`, promptui.IconGood),
+				Selected: fmt.Sprintf(
+					`{{ if ne .Title "%[1]s" }}{{ "%[2]s" | green }} {{ "Selected repository:" | faint }} {{ .Title }}{{ end }}`,
+					loadMoreRepos,
+					promptui.IconGood,
+				),
 			},
 		}
-		choose, _, err = prompt.Run()
+		if choose, _, err = prompt.RunCursorAt(choose, 0); err != nil {
+			return nil, err
+		}
+		if choose < len(repos) {
+			return &repos[choose], nil
+		}
+		if page, err = cloud.Repos(ctx, cloudapi.ReposInput{First: reposPageSize, After: page.PageInfo.EndCursor}); err != nil {
+			return nil, err
+		}
+		repos = append(repos, page.Repos...)
+	}
+}
+
+// createAtlasRepo offers to create a new Atlas Cloud repository when no existing one matches.
+// If the user declines, notFound is returned.
+func (i *InitActionCmd) createAtlasRepo(ctx context.Context, cloud cloudapi.API, notFound error) (*cloudapi.Repo, error) {
+	confirm := promptui.Prompt{
+		Label:     "No matching Atlas Cloud repository found, create a new one",
+		IsConfirm: true,
+		Stdin:     i.stdin,
+		Stdout:    i.promptOut(),
+		Templates: &promptui.PromptTemplates{
+			Success: fmt.Sprintf(`{{ "%s" | green }} {{ "Create Atlas Cloud repository: " | faint }}`, promptui.IconGood),
+		},
+	}
+	if _, err := confirm.Run(); err != nil {
+		if errors.Is(err, promptui.ErrAbort) || errors.Is(err, promptui.ErrEOF) {
+			return nil, notFound
+		}
+		return nil, err
+	}
+	input := cloudapi.CreateRepoInput{Slug: i.DirName, Driver: i.driver}
+	switch {
+	case i.DirName != "":
+		input.Type = cloudapi.DirectoryType
+	case i.To != "":
+		input.Type = cloudapi.SchemaType
+		// atlas://<slug>[:<tag>]
+		if slug, ok := strings.CutPrefix(i.To, "atlas://"); ok {
+			input.Slug, _, _ = strings.Cut(slug, ":")
+		}
+	default:
+		prompt := promptui.Select{
+			Label:    "Choose repository type",
+			HideHelp: true,
+			Items:    []string{"migration directory", "schema"},
+			Stdin:    i.stdin,
+			Stdout:   i.promptOut(),
+			Templates: &promptui.SelectTemplates{
+				Selected: fmt.Sprintf(`{{ "%s" | green }} {{ "Repository type:" | faint }} {{ . }}`, promptui.IconGood),
+			},
+		}
+		idx, _, err := prompt.Run()
+		if err != nil {
+			return nil, err
+		}
+		input.Type = cloudapi.DirectoryType
+		if idx == 1 {
+			input.Type = cloudapi.SchemaType
+		}
+	}
+	if input.Slug == "" {
+		prompt := promptui.Prompt{
+			Label:  "Enter the name of the new repository",
+			Stdin:  i.stdin,
+			Stdout: i.promptOut(),
+			Validate: func(s string) error {
+				if strings.TrimSpace(s) == "" {
+					return errors.New("repository name cannot be empty")
+				}
+				return nil
+			},
+			Templates: &promptui.PromptTemplates{
+				Success: fmt.Sprintf(`{{ "%s" | green }} {{ "Repository name: " | faint }}`, promptui.IconGood),
+			},
+		}
+		slug, err := prompt.Run()
+		if err != nil {
+			return nil, err
+		}
+		input.Slug = strings.TrimSpace(slug)
+	}
+	if input.Driver == "" {
+		if err := i.setDriver(); err != nil {
+			return nil, err
+		}
+		input.Driver = i.driver
+	}
+	repo, err := cloud.CreateRepo(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(i.out(), "%s %s %s\n",
+		promptui.IconGood,
+		promptui.Styler(promptui.FGFaint)("Created Atlas Cloud repository:"),
+		repo.URL,
+	)
+	return repo, nil
+}
+
+// checkTokenPermissions verifies that the token can push to the selected repository,
+// as the generated workflow fails otherwise. Personal tokens are allowed with a warning.
+func (i *InitActionCmd) checkTokenPermissions(ctx context.Context, cloud cloudapi.API, repo *cloudapi.Repo) error {
+	info, err := cloud.TokenInfo(ctx)
+	if err != nil {
+		// Permissions cannot be verified, let the workflow report them.
+		fmt.Fprintf(i.out(), "%s %s %v\n",
+			promptui.IconWarn,
+			promptui.Styler(promptui.FGFaint)("Could not verify the Atlas token permissions:"),
+			err)
+		return nil
+	}
+	if !info.CanPush(*repo) {
+		cmd := "migrate push"
+		if repo.Type == cloudapi.SchemaType {
+			cmd = "schema push"
+		}
+		return fmt.Errorf("the given Atlas token is not allowed to %s to repository %q, please use a bot token with the required permissions", cmd, repo.Slug)
+	}
+	if info.Type == cloudapi.PersonalToken {
+		fmt.Fprintf(i.out(), "%s %s\n",
+			promptui.IconWarn,
+			promptui.Styler(promptui.FGFaint)(fmt.Sprintf("The Atlas token belongs to %s, consider using a bot token for CI.", info.Name)))
+	}
+	return nil
+}
+
+// findRepo pages through the repositories matching the given filter and
+// returns the first one that satisfies match, or nil if none does.
+func findRepo(ctx context.Context, cloud cloudapi.API, filter *cloudapi.ReposFilter, match func(cloudapi.Repo) bool) (*cloudapi.Repo, error) {
+	input := cloudapi.ReposInput{First: reposPageSize, Filter: filter}
+	for {
+		page, err := cloud.Repos(ctx, input)
 		if err != nil {
 			return nil, err
 		}
+		if idx := slices.IndexFunc(page.Repos, match); idx != -1 {
+			return &page.Repos[idx], nil
+		}
+		if !page.PageInfo.HasNextPage {
+			return nil, nil
+		}
+		input.After = page.PageInfo.EndCursor
 	}
-	return &repos[choose], nil
 }
 
 func (i *InitActionCmd) setDesiredState() error {
@@ -171,8 +358,9 @@ func (i *InitActionCmd) setDesiredState() error {
 		return nil
 	}
 	prompt := promptui.Prompt{
-		Label: "Enter a URL of the desired schema state",
-		Stdin: i.stdin,
+		Label:  "Enter a URL of the desired schema state",
+		Stdin:  i.stdin,
+		Stdout: i.promptOut(),
 		Validate: func(input string) error {
 			if len(i.To) == 0 && strings.TrimSpace(input) == "" {
 				return errors.New("at least one URL is required for desired schema state")
@@ -196,8 +384,9 @@ func (i *InitActionCmd) setCurrentState() error {
 		return nil
 	}
 	prompt := promptui.Prompt{
-		Label: "Enter a URL of the current schema state",
-		Stdin: i.stdin,
+		Label:  "Enter a URL of the current schema state",
+		Stdin:  i.stdin,
+		Stdout: i.promptOut(),
 		Validate: func(input string) error {
 			if len(i.From) == 0 && strings.TrimSpace(input) == "" {
 				return errors.New("at least one URL is required for current schema state")
@@ -238,6 +427,7 @@ func (i *InitActionCmd) chooseDirPath(dirs []string) (string, error) {
 		HideHelp: true,
 		Items:    opts,
 		Stdin:    i.stdin,
+		Stdout:   i.promptOut(),
 		Templates: &promptui.SelectTemplates{
 			Selected: fmt.Sprintf(
 				`{{ if ne . "%[1]s" }}{{ "%[2]s" | green }} {{ "%[3]s" | faint }} {{ . }} {{ end }}`,
@@ -258,14 +448,15 @@ func (i *InitActionCmd) chooseDirPath(dirs []string) (string, error) {
 }
 
 func (i *InitActionCmd) setSchemaScope() error {
-	// sqlite has only one schema
-	if i.SchemaScope || i.driver == "SQLITE" || i.driver == "SPANNER" {
+	// sqlite has only one schema, and a custom dev URL sets the scope itself
+	if i.SchemaScope || i.driver == "SQLITE" || i.driver == "SPANNER" || i.DevDB == gen.DevDBCustom {
 		return nil
 	}
 	prompt := promptui.Select{
-		Label: "Do you manage a single schema or multiple? (used to limit the scope of the work done by Atlas)",
-		Stdin: i.stdin,
-		Items: []string{"single", "multiple"},
+		Label:  "Do you manage a single schema or multiple? (used to limit the scope of the work done by Atlas)",
+		Stdin:  i.stdin,
+		Stdout: i.promptOut(),
+		Items:  []string{"single", "multiple"},
 		Templates: &promptui.SelectTemplates{
 			Selected: fmt.Sprintf(`{{ "%s" | green }} {{ "Schema scope:" | faint }} {{ . }}`, promptui.IconGood),
 		},
@@ -282,10 +473,23 @@ func (i *InitActionCmd) setToken() error {
 	if i.Token != "" {
 		return nil
 	}
+	token, source, err := lookupToken(i.tokenSources, i.CloudURL)
+	if err != nil {
+		return err
+	}
+	if token != "" {
+		i.Token = token
+		fmt.Fprintf(i.out(), "%s %s %s\n",
+			promptui.IconGood,
+			promptui.Styler(promptui.FGFaint)("Atlas Cloud token:"),
+			"found in "+source)
+		return nil
+	}
 	prompt := promptui.Prompt{
-		Label: "Enter Atlas Cloud token",
-		Stdin: i.stdin,
-		Mask:  '*',
+		Label:  "Enter Atlas Cloud token",
+		Stdin:  i.stdin,
+		Stdout: i.promptOut(),
+		Mask:   '*',
 		Validate: func(s string) error {
 			if strings.TrimSpace(s) == "" {
 				return errors.New("token cannot be empty")
@@ -296,8 +500,7 @@ func (i *InitActionCmd) setToken() error {
 			Success: fmt.Sprintf(`{{ "%s" | green }} {{ "Atlas Cloud token: " | faint }}`, promptui.IconGood),
 		},
 	}
-	token, err := prompt.Run()
-	i.Token = token
+	i.Token, err = prompt.Run()
 	return err
 }
 
@@ -348,7 +551,10 @@ func (i *InitActionCmd) getEnvs(ctx context.Context, path string, cr RepoExplore
 					}
 					return ok
 				})
-				_, e.HasSchemaSrc = b.Attributes["src"]
+				var src *hclsyntax.Attribute
+				if src, e.HasSchemaSrc = b.Attributes["src"]; e.HasSchemaSrc {
+					e.SchemaSrc = schemaSrc(src)
+				}
 			}
 			envs = append(envs, e)
 		}
@@ -368,6 +574,30 @@ func (i *InitActionCmd) getEnvs(ctx context.Context, path string, cr RepoExplore
 	return envs, nil
 }
 
+// schemaSrc returns the URLs of the schema.src attribute, or nil
+// if it depends on variables, functions or other blocks.
+func schemaSrc(attr *hclsyntax.Attribute) []string {
+	v, diags := attr.Expr.Value(nil)
+	if diags.HasErrors() || !v.IsWhollyKnown() || v.IsNull() {
+		return nil
+	}
+	switch t := v.Type(); {
+	case t == cty.String:
+		return []string{v.AsString()}
+	case t.IsTupleType() || t.IsListType():
+		var srcs []string
+		for it := v.ElementIterator(); it.Next(); {
+			_, e := it.Element()
+			if e.IsNull() || e.Type() != cty.String {
+				return nil
+			}
+			srcs = append(srcs, e.AsString())
+		}
+		return srcs
+	}
+	return nil
+}
+
 func (i *InitActionCmd) chooseConfig(configs []string) (string, error) {
 	prompt := promptui.Select{
 		Label:    "Use config file?",
@@ -378,7 +608,8 @@ func (i *InitActionCmd) chooseConfig(configs []string) (string, error) {
 			Inactive: "{{ if eq . \"no\" }}  No{{ else }}  Use {{ . | bold }}{{ end }}",
 			Selected: fmt.Sprintf(`{{ "%s" | green }} {{ "Config file: " | faint }} {{ . }}`, promptui.IconGood),
 		},
-		Stdin: i.stdin,
+		Stdin:  i.stdin,
+		Stdout: i.promptOut(),
 	}
 	_, config, err := prompt.Run()
 	if config == "no" {
@@ -395,6 +626,7 @@ func (i *InitActionCmd) chooseEnv(envs []gen.Env) error {
 			HideHelp: true,
 			Items:    envs,
 			Stdin:    i.stdin,
+			Stdout:   i.promptOut(),
 			Templates: &promptui.SelectTemplates{
 				Active:   `▸ {{ or .Name "OTHER" }}`,
 				Inactive: `  {{ or .Name "OTHER" }}`,
@@ -421,8 +653,9 @@ func (i *InitActionCmd) chooseEnv(envs []gen.Env) error {
 
 func (i *InitActionCmd) promptForEnvName() (string, error) {
 	prompt := promptui.Prompt{
-		Label: "Enter the environment name",
-		Stdin: i.stdin,
+		Label:  "Enter the environment name",
+		Stdin:  i.stdin,
+		Stdout: i.promptOut(),
 		Templates: &promptui.PromptTemplates{
 			Success: fmt.Sprintf(`{{ "%s" | green }} {{ "Env block name: " | faint }}`, promptui.IconGood),
 		},
@@ -430,11 +663,27 @@ func (i *InitActionCmd) promptForEnvName() (string, error) {
 	return prompt.Run()
 }
 
+// promptOut returns the writer of the prompts. In JSON mode, they are written
+// to stderr to keep the output of the command machine-readable.
+func (i *InitActionCmd) promptOut() io.WriteCloser {
+	if i.enabled() {
+		return nopCloser{i.out()}
+	}
+	// Use the default of promptui.
+	return nil
+}
+
+// nopCloser wraps a writer with a no-op Close method.
+type nopCloser struct{ io.Writer }
+
+func (nopCloser) Close() error { return nil }
+
 func (i *InitActionCmd) openURL(url string) error {
 	prompt := promptui.Prompt{
 		Label:     "Open in browser",
 		IsConfirm: true,
 		Stdin:     i.stdin,
+		Stdout:    i.promptOut(),
 		Templates: &promptui.PromptTemplates{
 			Success: fmt.Sprintf(`{{ "%s" | green }} {{ "Open in browser: " | faint }}`, promptui.IconGood),
 		},
@@ -453,8 +702,9 @@ func (i *InitActionCmd) openURL(url string) error {
 
 func (i *InitActionCmd) promptForCustomPath() (string, error) {
 	prompt := promptui.Prompt{
-		Label: "Enter the path of the migration directory in your repository",
-		Stdin: i.stdin,
+		Label:  "Enter the path of the migration directory in your repository",
+		Stdin:  i.stdin,
+		Stdout: i.promptOut(),
 		Templates: &promptui.PromptTemplates{
 			Success: fmt.Sprintf(`{{ "%s" | green }} {{ "Migrations directory path: " | faint }}`, promptui.IconGood),
 		},
@@ -468,9 +718,10 @@ func (i *InitActionCmd) setSetupSchemaApply() error {
 		return nil
 	}
 	prompt := promptui.Select{
-		Label: "Do you want to setup the `schema apply` action?",
-		Stdin: i.stdin,
-		Items: []string{"yes", "no"},
+		Label:  "Do you want to setup the `schema apply` action?",
+		Stdin:  i.stdin,
+		Stdout: i.promptOut(),
+		Items:  []string{"yes", "no"},
 		Templates: &promptui.SelectTemplates{
 			Active:   "{{ if eq . \"no\" }}▸ No{{ else }}▸ Yes{{ end }}",
 			Inactive: "{{ if eq . \"no\" }}  No{{ else }}  Yes{{ end }}",
@@ -486,12 +737,46 @@ func (i *InitActionCmd) setSetupSchemaApply() error {
 }
 
 func (i *InitActionCmd) validateParams() error {
+	if i.CloudURL != "" {
+		u, err := url.Parse(i.CloudURL)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return fmt.Errorf("invalid Atlas Cloud URL %q, expected an http(s) URL", i.CloudURL)
+		}
+	}
+	if i.DevDB != "" && !slices.Contains(gen.DevDBModes, i.DevDB) {
+		return fmt.Errorf("invalid dev-db %q, expected one of: %s", i.DevDB, strings.Join(gen.DevDBModes, ", "))
+	}
+	if i.DevDB == gen.DevDBCustom && i.DevURLSecret == "" {
+		return errors.New("dev-url-secret is required for the custom dev-db")
+	}
+	if i.TimeoutMinutes < 0 {
+		return errors.New("timeout-minutes must be a positive number")
+	}
+	if i.SecretName != "" && (!secretNameRe.MatchString(i.SecretName) || strings.HasPrefix(strings.ToUpper(i.SecretName), "GITHUB_")) {
+		return fmt.Errorf("invalid secret name %q, expected alphanumeric characters or underscores, not starting with a number or GITHUB_", i.SecretName)
+	}
+	if i.Branch != "" && (strings.HasPrefix(i.Branch, "-") || strings.ContainsAny(i.Branch, " ~^:?*[\\")) {
+		return fmt.Errorf("invalid branch name %q", i.Branch)
+	}
+	for _, r := range i.Reviewers {
+		if org, team, ok := strings.Cut(r, "/"); r == "" || ok && (org == "" || team == "" || strings.Contains(team, "/")) {
+			return fmt.Errorf("invalid reviewer %q, expected a user login or <org>/<team>", r)
+		}
+	}
+	if i.Offline {
+		if i.From != "" || i.To != "" || i.flow == Declarative {
+			return errors.New("declarative flow requires Atlas Cloud and is not supported in offline mode")
+		}
+		if i.DirName != "" {
+			return errors.New("dirname is not applicable in offline mode")
+		}
+	}
 	switch i.flow {
 	case Versioned:
 		if i.DirPath == "" {
 			return errors.New("dirpath is required for versioned flow")
 		}
-		if i.DirName == "" {
+		if i.DirName == "" && !i.Offline {
 			return errors.New("dirname is required for versioned flow")
 		}
 		if i.From != "" || i.To != "" {
@@ -505,6 +790,114 @@ func (i *InitActionCmd) validateParams() error {
 	return nil
 }
 
+// secretNameRe matches the valid names of GitHub secrets.
+var secretNameRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// triggers returns the workflow triggers set by flags.
+func (i *InitActionCmd) triggers() (gen.Triggers, error) {
+	t := gen.Triggers{
+		Branches: i.TriggerBranches,
+		Paths:    i.TriggerPaths,
+	}
+	if i.MergeGroup != nil {
+		t.MergeGroup = *i.MergeGroup
+	}
+	envs := make(map[string]string)
+	for _, s := range i.DispatchInputs {
+		in, err := parseInput(s)
+		if err != nil {
+			return t, err
+		}
+		// Inputs are exposed to the steps as environment variables.
+		env := in.Env()
+		if strings.HasPrefix(env, "GITHUB_") {
+			return t, fmt.Errorf("dispatch input %q cannot start with GITHUB_, it is exposed as the %s environment variable", in.Name, env)
+		}
+		if other, ok := envs[env]; ok {
+			return t, fmt.Errorf("dispatch inputs %q and %q are both exposed as the %s environment variable", other, in.Name, env)
+		}
+		envs[env] = in.Name
+		t.Inputs = append(t.Inputs, in)
+	}
+	return t, nil
+}
+
+// inputNameRe matches the names GitHub allows for workflow_dispatch inputs.
+var inputNameRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+
+// parseInput parses a workflow_dispatch input in the NAME[:TYPE][=DEFAULT] format.
+func parseInput(s string) (gen.Input, error) {
+	var in gen.Input
+	spec, def, _ := strings.Cut(s, "=")
+	in.Name, in.Type, _ = strings.Cut(spec, ":")
+	in.Default = def
+	if in.Name == "" {
+		return in, fmt.Errorf("invalid dispatch input %q, expected NAME[:TYPE][=DEFAULT]", s)
+	}
+	if !inputNameRe.MatchString(in.Name) {
+		return in, fmt.Errorf("invalid dispatch input name %q, expected letters, digits, '-' and '_'", in.Name)
+	}
+	switch in.Type {
+	case "", "string", "boolean", "number", "environment":
+	default:
+		return in, fmt.Errorf("unsupported type %q of dispatch input %q, expected string, boolean, number or environment", in.Type, in.Name)
+	}
+	// Inputs without a default value must be provided.
+	in.Required = in.Default == ""
+	return in, nil
+}
+
+// prTemplate returns the template of the pull request, defining the "title" and "body" templates.
+// The first line of the --pr-template file is the title, and the rest is the body.
+func (i *InitActionCmd) prTemplate() (*template.Template, error) {
+	title, body := commitMsg, prBody
+	if i.PRTemplate != "" {
+		b, err := os.ReadFile(i.PRTemplate)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the pull request template: %w", err)
+		}
+		first, rest, _ := strings.Cut(string(b), "\n")
+		title, body = first, strings.TrimSpace(rest)
+	}
+	if i.PRTitle != "" {
+		title = i.PRTitle
+	}
+	if i.PRBody != "" {
+		body = i.PRBody
+	}
+	t, err := template.New("title").Funcs(template.FuncMap{"join": strings.Join}).Parse(title)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pull request title: %w", err)
+	}
+	if _, err = t.New("body").Parse(body); err != nil {
+		return nil, fmt.Errorf("invalid pull request body: %w", err)
+	}
+	return t, nil
+}
+
+// pullRequest returns the options of the pull request, executing its templates with the workflow config.
+func (i *InitActionCmd) pullRequest(t *template.Template, cfg *gen.Config) (*PullRequestOptions, error) {
+	var title, body strings.Builder
+	if err := t.ExecuteTemplate(&title, "title", cfg); err != nil {
+		return nil, fmt.Errorf("failed to execute the pull request title: %w", err)
+	}
+	if err := t.ExecuteTemplate(&body, "body", cfg); err != nil {
+		return nil, fmt.Errorf("failed to execute the pull request body: %w", err)
+	}
+	opts := &PullRequestOptions{
+		Title:     strings.TrimSpace(title.String()),
+		Body:      body.String(),
+		Labels:    i.Labels,
+		Reviewers: i.Reviewers,
+		Assignees: i.Assignees,
+		Draft:     i.Draft,
+	}
+	if opts.Title == "" {
+		return nil, errors.New("the pull request title is empty")
+	}
+	return opts, nil
+}
+
 func ptr[T any](v T) *T {
 	return &v
 }
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/cli/go-gh/pkg/repository"
+
+	"ariga.io/gh-atlas/cloudapi"
+)
+
+// ReportCmd is the command for showing the Atlas Cloud CI reports of a pull request.
+type ReportCmd struct {
+	cloudFlags
+	PR   int    `arg:"" optional:"" name:"pr-number" help:"Number of the pull request, defaults to the pull request of the current branch."`
+	Repo string `short:"R" help:"GitHub repository <owner>/<name>, defaults to the current repository."`
+	outputFlags
+	// branch returns the current git branch.
+	branch func(context.Context) (string, error) `hidden:""`
+}
+
+func (r *ReportCmd) Help() string {
+	return `Exits with a non-zero status code if any of the reports contains errors.
+
+Examples:
+	gh atlas report
+	gh atlas report 42 -R ariga/atlas-demo
+	gh atlas report --jq '.[] | select(.status != "SUCCESS") | .url'`
+}
+
+// Run the report command.
+func (r *ReportCmd) Run(ctx context.Context, client *githubClient, current repository.Repository) error {
+	var err error
+	if r.Repo != "" {
+		if current, err = repository.Parse(r.Repo); err != nil {
+			return err
+		}
+	}
+	var branch string
+	if r.PR == 0 {
+		if r.branch == nil {
+			r.branch = gitBranch
+		}
+		if branch, err = r.branch(ctx); err != nil {
+			return err
+		}
+	}
+	number, sha, err := NewRepository(client, current, "").PullRequestHead(ctx, r.PR, branch)
+	if err != nil {
+		return err
+	}
+	api, err := r.client()
+	if err != nil {
+		return err
+	}
+	reports, err := api.Reports(ctx, cloudapi.ReportsInput{
+		Repo:   current.Owner() + "/" + current.Name(),
+		Commit: sha,
+	})
+	if err != nil {
+		return err
+	}
+	if len(reports) == 0 {
+		return fmt.Errorf("no Atlas Cloud reports found for pull request #%d (commit %s)", number, sha)
+	}
+	if r.enabled() {
+		if err := r.print(r.out(), reports); err != nil {
+			return err
+		}
+		return reportsError(reports)
+	}
+	return renderReports(r.out(), reports)
+}
+
+// renderReports writes the reports and their diagnostics to w, and returns
+// an error if any of the reports contains diagnostics with error severity.
+func renderReports(w io.Writer, reports []cloudapi.Report) error {
+	for i, rep := range reports {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "%s report: %s\n", strings.ToLower(string(rep.Type)), rep.Status)
+		if rep.URL != "" {
+			fmt.Fprintf(w, "%s\n", rep.URL)
+		}
+		if len(rep.Diagnostics) == 0 {
+			fmt.Fprintln(w, "No issues found.")
+			continue
+		}
+		fmt.Fprintln(w)
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "SEVERITY\tFILE\tCODE\tMESSAGE")
+		for _, d := range rep.Diagnostics {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", d.Severity, d.File, d.Code, d.Message)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+	return reportsError(reports)
+}
+
+// reportsError returns an error if any of the reports contains diagnostics with error severity.
+func reportsError(reports []cloudapi.Report) error {
+	var errs int
+	for _, rep := range reports {
+		for _, d := range rep.Diagnostics {
+			if d.Severity == cloudapi.SeverityError {
+				errs++
+			}
+		}
+	}
+	if errs > 0 {
+		return fmt.Errorf("found %d error(s) in the Atlas Cloud reports", errs)
+	}
+	return nil
+}
+
+// gitBranch returns the name of the branch checked out in the working directory.
+func gitBranch(ctx context.Context) (string, error) {
+	out, err := exec.CommandContext(ctx, "git", "branch", "--show-current").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get the current branch: %w", err)
+	}
+	branch := strings.TrimSpace(string(out))
+	if branch == "" {
+		return "", errors.New("no branch is checked out, please provide a pull request number")
+	}
+	return branch, nil
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"ariga.io/gh-atlas/cloudapi"
+	"github.com/cli/go-gh/pkg/repository"
+	"github.com/google/go-github/v49/github"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReportCmd(t *testing.T) {
+	repo, err := repository.Parse("owner/repo")
+	require.NoError(t, err)
+	client := createGHClient(&mockService{}, &mockService{})
+	client.PullRequests = &mockPullRequestsService{
+		prs: []*github.PullRequest{
+			// The branch of a fork, named as a branch of the repository.
+			{
+				Number: github.Int(4),
+				Head:   &github.PullRequestBranch{SHA: github.String("sha4"), Ref: github.String("feature"), Repo: &github.Repository{FullName: github.String("octocat/repo")}},
+			},
+			{
+				Number: github.Int(1),
+				Head:   &github.PullRequestBranch{SHA: github.String("sha1"), Ref: github.String("feature"), Repo: &github.Repository{FullName: github.String("owner/repo")}},
+			},
+			{
+				Number: github.Int(2),
+				Head:   &github.PullRequestBranch{SHA: github.String("sha2"), Ref: github.String("broken"), Repo: &github.Repository{FullName: github.String("owner/repo")}},
+			},
+			{
+				Number: github.Int(5),
+				Head:   &github.PullRequestBranch{SHA: github.String("sha1"), Ref: github.String("atlas-ci-abc123"), Repo: &github.Repository{FullName: github.String("octocat/repo")}},
+			},
+			{
+				Number: github.Int(3),
+				Head:   &github.PullRequestBranch{SHA: github.String("sha3"), Ref: github.String("pending"), Repo: &github.Repository{FullName: github.String("owner/repo")}},
+			},
+		},
+	}
+	api := &mockCloudAPI{
+		reports: map[string][]cloudapi.Report{
+			"sha1": {
+				{Type: cloudapi.LintReport, Status: "SUCCESS", URL: "https://ariga.atlasgo.cloud/ci/1"},
+			},
+			"sha2": {
+				{
+					Type:   cloudapi.LintReport,
+					Status: "FAILED",
+					URL:    "https://ariga.atlasgo.cloud/ci/2",
+					Diagnostics: []cloudapi.Diagnostic{
+						{File: "20240101_init.sql", Code: "DS102", Message: "Dropping table \"users\"", Severity: cloudapi.SeverityError},
+						{File: "20240101_init.sql", Code: "MF103", Message: "Adding a non-nullable column", Severity: cloudapi.SeverityWarning},
+					},
+				},
+			},
+		},
+	}
+	branch := func(b string) func(context.Context) (string, error) {
+		return func(context.Context) (string, error) { return b, nil }
+	}
+	tests := []struct {
+		name    string
+		cmd     *ReportCmd
+		out     string
+		wantErr string
+	}{
+		{
+			name: "by number",
+			cmd:  &ReportCmd{PR: 1},
+			out:  "lint report: SUCCESS\nhttps://ariga.atlasgo.cloud/ci/1\nNo issues found.\n",
+		},
+		{
+			name: "by current branch",
+			cmd:  &ReportCmd{branch: branch("feature")},
+			out:  "lint report: SUCCESS\nhttps://ariga.atlasgo.cloud/ci/1\nNo issues found.\n",
+		},
+		{
+			name: "by branch of a fork",
+			cmd:  &ReportCmd{branch: branch("atlas-ci-abc123")},
+			out:  "lint report: SUCCESS\nhttps://ariga.atlasgo.cloud/ci/1\nNo issues found.\n",
+		},
+		{
+			name: "with errors",
+			cmd:  &ReportCmd{PR: 2},
+			out: `lint report: FAILED
+https://ariga.atlasgo.cloud/ci/2
+
+SEVERITY  FILE               CODE   MESSAGE
+ERROR     20240101_init.sql  DS102  Dropping table "users"
+WARNING   20240101_init.sql  MF103  Adding a non-nullable column
+`,
+			wantErr: "found 1 error(s) in the Atlas Cloud reports",
+		},
+		{
+			name:    "with errors, jq",
+			cmd:     &ReportCmd{PR: 2, outputFlags: outputFlags{JQ: `.[].diagnostics[] | select(.severity == "ERROR") | .code`}},
+			out:     "DS102\n",
+			wantErr: "found 1 error(s) in the Atlas Cloud reports",
+		},
+		{
+			name:    "no pull request for branch",
+			cmd:     &ReportCmd{branch: branch("main")},
+			wantErr: `no open pull request found for branch "main"`,
+		},
+		{
+			name:    "no reports",
+			cmd:     &ReportCmd{PR: 3},
+			wantErr: "no Atlas Cloud reports found for pull request #3 (commit sha3)",
+		},
+		{
+			name:    "unknown pull request",
+			cmd:     &ReportCmd{PR: 6},
+			wantErr: "pull request not found",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			tt.cmd.api = api
+			tt.cmd.stdout = &out
+			err := tt.cmd.Run(context.Background(), client, repo)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, tt.out, out.String())
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"ariga.io/gh-atlas/cloudapi"
+)
+
+// tokenSource looks up an Atlas Cloud token from a single location.
+type tokenSource struct {
+	// name describes where the token was found.
+	name string
+	// token returns the token for the given Atlas Cloud host,
+	// or an empty string if the source has no token.
+	token func(host string) (string, error)
+}
+
+// defaultTokenSources are the locations searched, in order, for an Atlas Cloud
+// token when it was not provided by the --token flag or ATLAS_CLOUD_TOKEN variable.
+var defaultTokenSources = []tokenSource{
+	{name: "atlas login credentials", token: credentialsToken},
+}
+
+// credentialsToken reads the token stored by the `atlas login` command. The file does not
+// record the endpoint the token was issued by, so it is used only for the default one.
+func credentialsToken(host string) (string, error) {
+	if u, err := url.Parse(cloudapi.DefaultURL); err != nil || host != u.Host {
+		return "", nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", nil
+	}
+	return readCredentials(filepath.Join(home, ".atlas", "credentials.json"))
+}
+
+// readCredentials reads the token from an Atlas credentials file.
+func readCredentials(path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	var creds struct {
+		Token string `json:"token"`
+	}
+	if err := json.Unmarshal(b, &creds); err != nil {
+		return "", fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return creds.Token, nil
+}
+
+// lookupToken searches the token sources in order and returns the
+// first token found for the Atlas Cloud endpoint, and its source name.
+func lookupToken(sources []tokenSource, endpoint string) (string, string, error) {
+	if endpoint == "" {
+		endpoint = cloudapi.DefaultURL
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", "", err
+	}
+	for _, s := range sources {
+		token, err := s.token(u.Host)
+		if err != nil {
+			return "", "", err
+		}
+		if token != "" {
+			return token, s.name, nil
+		}
+	}
+	return "", "", nil
+}
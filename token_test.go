@@ -0,0 +1,88 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadCredentials(t *testing.T) {
+	dir := t.TempDir()
+	token, err := readCredentials(filepath.Join(dir, "credentials.json"))
+	require.NoError(t, err)
+	require.Empty(t, token)
+
+	path := filepath.Join(dir, "valid.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"token":"atlas-token"}`), 0600))
+	token, err = readCredentials(path)
+	require.NoError(t, err)
+	require.Equal(t, "atlas-token", token)
+
+	path = filepath.Join(dir, "invalid.json")
+	require.NoError(t, os.WriteFile(path, []byte(`token`), 0600))
+	_, err = readCredentials(path)
+	require.ErrorContains(t, err, "failed to parse")
+}
+
+func TestCredentialsToken(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	require.NoError(t, os.MkdirAll(filepath.Join(home, ".atlas"), 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(home, ".atlas", "credentials.json"), []byte(`{"token":"atlas-token"}`), 0600))
+	token, err := credentialsToken("api.atlasgo.cloud")
+	require.NoError(t, err)
+	require.Equal(t, "atlas-token", token)
+
+	// Tokens of the default Atlas Cloud are not sent to other endpoints.
+	token, err = credentialsToken("api.eu.atlasgo.cloud")
+	require.NoError(t, err)
+	require.Empty(t, token)
+}
+
+func TestInitActionCmd_setToken(t *testing.T) {
+	var hosts []string
+	source := func(token string) func(string) (string, error) {
+		return func(host string) (string, error) {
+			hosts = append(hosts, host)
+			return token, nil
+		}
+	}
+	cmd := &InitActionCmd{
+		CloudURL: "https://api.eu.atlasgo.cloud/query",
+		tokenSources: []tokenSource{
+			{name: "empty", token: source("")},
+			{name: "first", token: source("first-token")},
+			{name: "second", token: source("second-token")},
+		},
+	}
+	require.NoError(t, cmd.setToken())
+	require.Equal(t, "first-token", cmd.Token)
+	require.Equal(t, []string{"api.eu.atlasgo.cloud", "api.eu.atlasgo.cloud"}, hosts)
+
+	// Token given by flag or environment takes precedence.
+	hosts = nil
+	cmd.Token = "flag-token"
+	require.NoError(t, cmd.setToken())
+	require.Equal(t, "flag-token", cmd.Token)
+	require.Empty(t, hosts)
+
+	// Errors are reported.
+	cmd = &InitActionCmd{
+		tokenSources: []tokenSource{
+			{name: "file", token: func(string) (string, error) { return "", errors.New("permission denied") }},
+		},
+	}
+	require.EqualError(t, cmd.setToken(), "permission denied")
+}
+
+func TestInitActionCmd_validateCloudURL(t *testing.T) {
+	for _, u := range []string{"api.atlasgo.cloud", "ftp://api.atlasgo.cloud", "https://", "://bad"} {
+		cmd := &InitActionCmd{CloudURL: u}
+		require.Error(t, cmd.validateParams(), u)
+	}
+	cmd := &InitActionCmd{CloudURL: "https://api.atlasgo.cloud/query"}
+	require.NoError(t, cmd.validateParams())
+}